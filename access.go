@@ -0,0 +1,168 @@
+package log
+
+import (
+	"net/http"
+	"time"
+)
+
+// AccessField selects an optional field of an access log record. Fields combine with |.
+type AccessField uint
+
+const (
+	// AccessUserAgent adds the User-Agent header as user_agent.
+	AccessUserAgent AccessField = 1 << iota
+	// AccessReferer adds the Referer header as referer.
+	AccessReferer
+	// AccessBytesIn adds the size of the request body, when known, as bytes_in.
+	AccessBytesIn
+	// AccessBytesOut adds the size of the response body as bytes_out.
+	AccessBytesOut
+	// AccessRoute adds the matched route pattern, where the router reports one, as route.
+	AccessRoute
+
+	// AllAccessFields selects every optional field.
+	AllAccessFields = AccessUserAgent | AccessReferer | AccessBytesIn | AccessBytesOut | AccessRoute
+)
+
+// Access describes a served request. It is the data of the records written by the access log
+// middleware in this package and its adapters, which keeps their shape the same everywhere.
+// remote, uri, status, latency and request_id are always written; the fields selected by
+// Fields are added on top, so teams can trade detail against volume and cardinality.
+type Access struct {
+	Method    string
+	Remote    string
+	URI       string
+	Status    int
+	Latency   time.Duration
+	RequestID string
+	UserAgent string
+	Referer   string
+	Route     string
+	BytesIn   int64
+	BytesOut  int64
+	Fields    AccessField
+}
+
+// Log implements Loggable.
+func (a Access) Log() map[string]any {
+	data := map[string]any{
+		"remote":  a.Remote,
+		"uri":     a.URI,
+		"status":  a.Status,
+		"latency": a.Latency.String(),
+	}
+
+	if a.RequestID != "" {
+		data["request_id"] = a.RequestID
+	}
+
+	if a.Fields&AccessUserAgent != 0 {
+		data["user_agent"] = a.UserAgent
+	}
+
+	if a.Fields&AccessReferer != 0 {
+		data["referer"] = a.Referer
+	}
+
+	if a.Fields&AccessBytesIn != 0 {
+		data["bytes_in"] = a.BytesIn
+	}
+
+	if a.Fields&AccessBytesOut != 0 {
+		data["bytes_out"] = a.BytesOut
+	}
+
+	if a.Fields&AccessRoute != 0 && a.Route != "" {
+		data["route"] = a.Route
+	}
+
+	return data
+}
+
+// AccessHandler wraps next with an access log written through l, one InfoLevel record per
+// request with the method as message and Access as data. fields selects the optional fields.
+// Requests get an id as with RequestIDMiddleware, and handlers find a logger carrying it with
+// FromContext. The records carry no src, which would only point here.
+//
+//	http.Handle("/", logger.AccessHandler(handler, log.AccessUserAgent|log.AccessBytesOut))
+func (l *Logger) AccessHandler(next http.Handler, fields ...AccessField) http.Handler {
+	access := l.With()
+	access.SetIncludeSource(false)
+
+	var selected AccessField
+
+	for _, f := range fields {
+		selected |= f
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id, r := l.assignRequestID(w, r)
+		rec := &accessRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			// nothing was written, net/http answers with 200
+			rec.status = http.StatusOK
+		}
+
+		bytesIn := r.ContentLength
+
+		if bytesIn < 0 {
+			bytesIn = 0
+		}
+
+		access.With(Access{
+			Method:    r.Method,
+			Remote:    r.RemoteAddr,
+			URI:       r.RequestURI,
+			Status:    rec.status,
+			Latency:   time.Since(start),
+			RequestID: id,
+			UserAgent: r.UserAgent(),
+			Referer:   r.Referer(),
+			BytesIn:   bytesIn,
+			BytesOut:  rec.bytes,
+			Fields:    selected,
+		}).Info(r.Method)
+	})
+}
+
+// accessRecorder captures the status and size of a response.
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (r *accessRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *accessRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+
+	handler := l.AccessHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}), AccessBytesIn|AccessBytesOut, AccessUserAgent)
+
+	req := httptest.NewRequest(http.MethodPost, "/users?id=1", strings.NewReader("body"))
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(RequestIDHeader, "req-1")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := decodeRecords(t, &buf)
+
+	if len(records) != 1 {
+		t.Fatalf("wrote %d records, want 1", len(records))
+	}
+
+	rec := records[0]
+
+	if rec.Level != "info" || rec.Msg != http.MethodPost || rec.Src.File != "" {
+		t.Errorf("record = %s %q src %+v, want info POST without src", rec.Level, rec.Msg, rec.Src)
+	}
+
+	want := map[string]any{
+		"remote":     req.RemoteAddr,
+		"uri":        "/users?id=1",
+		"status":     201.0,
+		"request_id": "req-1",
+		"user_agent": "test-agent",
+		"bytes_in":   4.0,
+		"bytes_out":  5.0,
+	}
+
+	for key, value := range want {
+		if rec.Data[key] != value {
+			t.Errorf("%s = %v, want %v", key, rec.Data[key], value)
+		}
+	}
+
+	latency, err := time.ParseDuration(rec.Data["latency"].(string))
+
+	if err != nil || latency < 5*time.Millisecond {
+		t.Errorf("latency = %v, want at least the 5ms the handler took", rec.Data["latency"])
+	}
+
+	if _, ok := rec.Data["referer"]; ok {
+		t.Errorf("referer written without AccessReferer: %v", rec.Data)
+	}
+}
+
+func TestAccessHandlerDefaultsToOK(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	handler := l.AccessHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	records := decodeRecords(t, &buf)
+
+	if len(records) != 1 || records[0].Data["status"] != 200.0 {
+		t.Fatalf("wrote %v, want one record with status 200", records)
+	}
+
+	if _, ok := records[0].Data["bytes_out"]; ok {
+		t.Errorf("bytes_out written without AccessBytesOut: %v", records[0].Data)
+	}
+}
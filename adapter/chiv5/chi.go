@@ -0,0 +1,89 @@
+// Package chiv5 adapts log to github.com/go-chi/chi/v5. It is a module of its own so that only
+// programs using it depend on chi:
+//
+//	r := chi.NewRouter()
+//	r.Use(chiv5.ForChi(logger))
+package chiv5
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/crit/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ForChi returns a chi middleware that writes one record per request through logger, in the
+// same shape as adapter.ForEcho. The matched route pattern, such as "/users/{id}", is recorded
+// rather than the request path alone so the field stays low in cardinality. The request id is
+// the one set by chi's RequestID middleware when present, otherwise it is assigned as in
+// adapter.ForEcho, and handlers get a logger carrying it from log.FromContext(r.Context()).
+//
+// The records hold log.Access data, and fields opts into its optional fields as in
+// adapter.ForEcho. The route is always included.
+func ForChi(logger *log.Logger, fields ...log.AccessField) func(http.Handler) http.Handler {
+	access := logger.With()
+	access.SetIncludeSource(false)
+
+	selected := log.AccessRoute
+
+	for _, f := range fields {
+		selected |= f
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			id := middleware.GetReqID(r.Context())
+
+			if id == "" {
+				id = r.Header.Get(log.RequestIDHeader)
+			}
+
+			if id == "" {
+				id = log.NewRequestID()
+			}
+
+			w.Header().Set(log.RequestIDHeader, id)
+			r = r.WithContext(log.NewContext(r.Context(), logger.WithRequestID(id)))
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+
+			if status == 0 {
+				// nothing was written, net/http answers with 200
+				status = http.StatusOK
+			}
+
+			route := ""
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			bytesIn := r.ContentLength
+
+			if bytesIn < 0 {
+				bytesIn = 0
+			}
+
+			access.With(log.Access{
+				Method:    r.Method,
+				Remote:    r.RemoteAddr,
+				URI:       r.RequestURI,
+				Status:    status,
+				Latency:   time.Since(start),
+				RequestID: id,
+				UserAgent: r.UserAgent(),
+				Referer:   r.Referer(),
+				Route:     route,
+				BytesIn:   bytesIn,
+				BytesOut:  int64(ww.BytesWritten()),
+				Fields:    selected,
+			}).Info(r.Method)
+		})
+	}
+}
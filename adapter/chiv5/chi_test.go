@@ -0,0 +1,87 @@
+package chiv5
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crit/log"
+	"github.com/crit/log/testlog"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestForChi(t *testing.T) {
+	logger, sink := testlog.New()
+
+	r := chi.NewRouter()
+	r.Use(ForChi(logger))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("loading user")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(log.RequestIDHeader, "req-1")
+	res := httptest.NewRecorder()
+
+	r.ServeHTTP(res, req)
+
+	if res.Header().Get(log.RequestIDHeader) != "req-1" {
+		t.Errorf("response request id = %q", res.Header().Get(log.RequestIDHeader))
+	}
+
+	records := sink.Records()
+
+	if len(records) != 2 {
+		t.Fatalf("wrote %d records, want 2", len(records))
+	}
+
+	if records[0].Msg != "loading user" || records[0].Data["request_id"] != "req-1" {
+		t.Errorf("handler record = %+v", records[0])
+	}
+
+	access := records[1]
+
+	if access.Msg != http.MethodGet || access.App != logger.AppName() || !access.Src.IsZero() {
+		t.Errorf("access record = %+v", access)
+	}
+
+	want := map[string]any{
+		"status":     http.StatusTeapot,
+		"uri":        "/users/42",
+		"route":      "/users/{id}",
+		"request_id": "req-1",
+	}
+
+	for key, value := range want {
+		if access.Data[key] != value {
+			t.Errorf("%s = %v (%T), want %v", key, access.Data[key], access.Data[key], value)
+		}
+	}
+}
+
+func TestForChiUsesChiRequestID(t *testing.T) {
+	logger, sink := testlog.New()
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, ForChi(logger))
+	r.Get("/", func(http.ResponseWriter, *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "chi-id")
+	res := httptest.NewRecorder()
+
+	r.ServeHTTP(res, req)
+
+	access := sink.Last()
+
+	if access.Data["request_id"] != "chi-id" {
+		t.Errorf("request_id = %v, want chi-id", access.Data["request_id"])
+	}
+
+	if access.Data["status"] != http.StatusOK {
+		t.Errorf("status = %v, want 200 for a handler that writes nothing", access.Data["status"])
+	}
+}
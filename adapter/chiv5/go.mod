@@ -0,0 +1,8 @@
+module github.com/crit/log/adapter/chiv5
+
+go 1.25.0
+
+require (
+	github.com/crit/log v0.0.0-00010101000000-000000000000
+	github.com/go-chi/chi/v5 v5.3.2
+)
@@ -1,59 +1,78 @@
 package adapter
 
 import (
-	"fmt"
-	"path/filepath"
-	"runtime"
-	"strings"
+	"time"
 
 	"github.com/crit/log"
 	"github.com/labstack/echo"
-	"github.com/labstack/echo/middleware"
 )
 
-func ForEcho(logger *log.Logger) echo.MiddlewareFunc {
-	fname := "???"
-	lnum := 0
+// ForEcho returns a middleware that writes one record per request through logger, so its
+// formatter, redaction and hooks apply to access logs like any other record. The records
+// carry no src: the only caller would be the middleware itself, which says nothing about
+// the request.
+//
+// Each request is given an id, taken from the X-Request-Id header or generated, which is
+// echoed in the response and recorded as request_id. Handlers get a logger carrying the id
+// from log.FromContext(c.Request().Context()).
+//
+// The records hold log.Access data. fields opts into its optional fields, such as the user
+// agent or echo's matched route:
+//
+//	e.Use(adapter.ForEcho(logger, log.AccessUserAgent|log.AccessRoute))
+func ForEcho(logger *log.Logger, fields ...log.AccessField) echo.MiddlewareFunc {
+	access := logger.With()
+	access.SetIncludeSource(false)
 
-	_, file, line, ok := runtime.Caller(1)
+	var selected log.AccessField
 
-	if ok {
-		fname = srcFileParse(file)
-		lnum = line
+	for _, f := range fields {
+		selected |= f
 	}
 
-	// format needed by LoggerWithConfig; notice that we are adding data to this string
-	// using fmt.Sprintf. Namely, app, src.file and src.line entries. This does have a
-	// problem that all logs are going to say the source is the same file/line. But since
-	// these logs are usually from the framework itself, its fine for now.
-	f := `{"time":"${time_rfc3339}","app":"%s","level":"info","msg":"${method}",` +
-		`"data":{"remote":"${remote_ip}","uri":"${uri}","status":${status},` +
-		`"latency":"${latency_human}"},"src":{"file":"%s","line":%d}}`
-
-	return middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: fmt.Sprintf(f, logger.AppName(), fname, lnum),
-		Output: logger.Out,
-	})
-}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := c.Response()
+			start := time.Now()
+			id := req.Header.Get(log.RequestIDHeader)
 
-// srcFileParse returns either the filename and extension, or the last directory
-// (which is also usually the package name in Go) with the filename and extension.
-// "project/src/model/user.go" => "model/user.go"
-// "main.go" => "main.go
-func srcFileParse(filename string) string {
-	// "project/src/model/user.go" => "project/src/model", "user.go"
-	dir, file := filepath.Split(filename)
-
-	// "project/src/model" => ["project", "src", "model"]
-	parts := strings.FieldsFunc(dir, func(c rune) bool {
-		return c == filepath.Separator
-	})
-
-	if len(parts) > 0 {
-		// => "model/user.go"
-		return filepath.Join(parts[len(parts)-1], file)
-	}
+			if id == "" {
+				id = log.NewRequestID()
+			}
+
+			res.Header().Set(log.RequestIDHeader, id)
+			c.SetRequest(req.WithContext(log.NewContext(req.Context(), logger.WithRequestID(id))))
+
+			err := next(c)
+
+			if err != nil {
+				// let echo write the error response so the logged status is the one sent
+				c.Error(err)
+			}
 
-	// "user.go"
-	return file
+			bytesIn := req.ContentLength
+
+			if bytesIn < 0 {
+				bytesIn = 0
+			}
+
+			access.With(log.Access{
+				Method:    req.Method,
+				Remote:    c.RealIP(),
+				URI:       req.RequestURI,
+				Status:    res.Status,
+				Latency:   time.Since(start),
+				RequestID: id,
+				UserAgent: req.UserAgent(),
+				Referer:   req.Referer(),
+				Route:     c.Path(),
+				BytesIn:   bytesIn,
+				BytesOut:  res.Size,
+				Fields:    selected,
+			}).Info(req.Method)
+
+			return nil
+		}
+	}
 }
@@ -0,0 +1,106 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/crit/log"
+	"github.com/labstack/echo"
+)
+
+// decodeLines parses each line of buf as a JSON object.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var out []map[string]any
+
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]any
+
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+
+		out = append(out, rec)
+	}
+
+	return out
+}
+
+// shape describes the field names of a decoded record and the JSON kind of each value,
+// descending into src.
+func shape(rec map[string]any) map[string]string {
+	out := map[string]string{}
+
+	for key, value := range rec {
+		out[key] = reflect.TypeOf(value).String()
+	}
+
+	if src, ok := rec["src"].(map[string]any); ok {
+		for key, value := range src {
+			out["src."+key] = reflect.TypeOf(value).String()
+		}
+	}
+
+	return out
+}
+
+func keys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+
+	for key := range m {
+		out = append(out, key)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// TestForEchoMatchesCoreRecords checks that access logs and handler records written through
+// ForEcho use the same field names and value shapes as records logged directly, so both can
+// share one index.
+func TestForEchoMatchesCoreRecords(t *testing.T) {
+	var core, served bytes.Buffer
+
+	log.NewWithWriter("api", log.DebugLevel, &core).With(log.Data{"user": 42}).Info("direct")
+
+	logger := log.NewWithWriter("api", log.DebugLevel, &served)
+
+	e := echo.New()
+	e.Use(ForEcho(logger))
+	e.GET("/users/:id", func(c echo.Context) error {
+		log.FromContext(c.Request().Context()).Info("loading user")
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	want := shape(decodeLines(t, &core)[0])
+	records := decodeLines(t, &served)
+
+	if len(records) != 2 {
+		t.Fatalf("wrote %d records, want 2", len(records))
+	}
+
+	if got := shape(records[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("handler record fields %v, core record fields %v", keys(got), keys(want))
+	}
+
+	// access records carry no src, and otherwise look like any other record
+	for key := range want {
+		if key == "src" || strings.HasPrefix(key, "src.") {
+			delete(want, key)
+		}
+	}
+
+	if got := shape(records[1]); !reflect.DeepEqual(got, want) {
+		t.Errorf("access record fields %v, core record fields %v", keys(got), keys(want))
+	}
+}
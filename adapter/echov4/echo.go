@@ -0,0 +1,74 @@
+// Package echov4 adapts log to github.com/labstack/echo/v4. It mirrors adapter.ForEcho,
+// which targets the older github.com/labstack/echo import path, and is a module of its own so
+// each import path is only required by the programs that use it:
+//
+//	e := echo.New()
+//	e.Use(echov4.ForEcho(logger))
+package echov4
+
+import (
+	"time"
+
+	"github.com/crit/log"
+	"github.com/labstack/echo/v4"
+)
+
+// ForEcho returns a middleware that writes one record per request through logger. Records
+// have the same shape as those written by adapter.ForEcho and, like them, carry no src. See
+// adapter.ForEcho for how request ids are assigned and which fields may be added.
+func ForEcho(logger *log.Logger, fields ...log.AccessField) echo.MiddlewareFunc {
+	access := logger.With()
+	access.SetIncludeSource(false)
+
+	var selected log.AccessField
+
+	for _, f := range fields {
+		selected |= f
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := c.Response()
+			start := time.Now()
+			id := req.Header.Get(log.RequestIDHeader)
+
+			if id == "" {
+				id = log.NewRequestID()
+			}
+
+			res.Header().Set(log.RequestIDHeader, id)
+			c.SetRequest(req.WithContext(log.NewContext(req.Context(), logger.WithRequestID(id))))
+
+			err := next(c)
+
+			if err != nil {
+				// let echo write the error response so the logged status is the one sent
+				c.Error(err)
+			}
+
+			bytesIn := req.ContentLength
+
+			if bytesIn < 0 {
+				bytesIn = 0
+			}
+
+			access.With(log.Access{
+				Method:    req.Method,
+				Remote:    c.RealIP(),
+				URI:       req.RequestURI,
+				Status:    res.Status,
+				Latency:   time.Since(start),
+				RequestID: id,
+				UserAgent: req.UserAgent(),
+				Referer:   req.Referer(),
+				Route:     c.Path(),
+				BytesIn:   bytesIn,
+				BytesOut:  res.Size,
+				Fields:    selected,
+			}).Info(req.Method)
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package echov4
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crit/log"
+	"github.com/crit/log/testlog"
+	"github.com/labstack/echo/v4"
+)
+
+func ExampleForEcho() {
+	logger := log.New("api", log.InfoLevel)
+
+	e := echo.New()
+	e.Use(ForEcho(logger, log.AccessRoute))
+	e.GET("/users/:id", func(c echo.Context) error {
+		log.FromContext(c.Request().Context()).Info("loading user")
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+func TestForEcho(t *testing.T) {
+	logger, sink := testlog.New()
+
+	e := echo.New()
+	e.Use(ForEcho(logger, log.AccessRoute))
+	e.GET("/users/:id", func(c echo.Context) error {
+		log.FromContext(c.Request().Context()).Info("loading user")
+		return c.String(http.StatusTeapot, "short and stout")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(log.RequestIDHeader, "req-1")
+	res := httptest.NewRecorder()
+
+	e.ServeHTTP(res, req)
+
+	if res.Header().Get(log.RequestIDHeader) != "req-1" {
+		t.Errorf("response request id = %q", res.Header().Get(log.RequestIDHeader))
+	}
+
+	records := sink.Records()
+
+	if len(records) != 2 {
+		t.Fatalf("wrote %d records, want 2", len(records))
+	}
+
+	if records[0].Msg != "loading user" || records[0].Data["request_id"] != "req-1" {
+		t.Errorf("handler record = %+v", records[0])
+	}
+
+	access := records[1]
+
+	if access.Msg != http.MethodGet || !access.Src.IsZero() {
+		t.Errorf("access record = %+v", access)
+	}
+
+	want := map[string]any{
+		"status":     http.StatusTeapot,
+		"uri":        "/users/42",
+		"route":      "/users/:id",
+		"request_id": "req-1",
+	}
+
+	for key, value := range want {
+		if access.Data[key] != value {
+			t.Errorf("%s = %v (%T), want %v", key, access.Data[key], access.Data[key], value)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package oteltrace correlates records with OpenTelemetry traces. It is a module of its own so
+// that only programs using it depend on go.opentelemetry.io/otel.
+package oteltrace
+
+import (
+	"context"
+
+	"github.com/crit/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTelTrace returns the trace_id and span_id of the OpenTelemetry span active in ctx, as hex
+// strings, or nil when there is none. Register it once at startup so loggers obtained with
+// log.FromContext or WithContext correlate with traces:
+//
+//	log.RegisterContextExtractor(oteltrace.OTelTrace)
+func OTelTrace(ctx context.Context) log.Loggable {
+	sc := trace.SpanContextFromContext(ctx)
+
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return log.Data{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
@@ -0,0 +1,64 @@
+package oteltrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crit/log"
+	"github.com/crit/log/testlog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestOTelTrace(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	if !span.IsRecording() {
+		t.Fatal("span is not recording")
+	}
+
+	data := OTelTrace(ctx)
+
+	if data == nil {
+		t.Fatal("no ids for a context with a span")
+	}
+
+	sc := span.SpanContext()
+	fields := data.Log()
+
+	if fields["trace_id"] != sc.TraceID().String() || fields["span_id"] != sc.SpanID().String() {
+		t.Errorf("got %v, want trace %s span %s", fields, sc.TraceID(), sc.SpanID())
+	}
+}
+
+func TestOTelTraceWithoutSpan(t *testing.T) {
+	if data := OTelTrace(context.Background()); data != nil {
+		t.Errorf("got %v for a context without a span", data.Log())
+	}
+}
+
+func TestOTelTraceExtractor(t *testing.T) {
+	log.RegisterContextExtractor(OTelTrace)
+
+	provider := sdktrace.NewTracerProvider()
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	logger, sink := testlog.New()
+	logger.WithContext(ctx).Info("handled")
+
+	if got := sink.Last().Data["trace_id"]; got != span.SpanContext().TraceID().String() {
+		t.Errorf("record trace_id = %v, want %s", got, span.SpanContext().TraceID())
+	}
+
+	logger.WithContext(context.Background()).Info("no span")
+
+	if _, ok := sink.Last().Data["trace_id"]; ok {
+		t.Error("record without a span has a trace_id")
+	}
+}
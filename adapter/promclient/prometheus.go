@@ -0,0 +1,58 @@
+// Package promclient exports the record counts of a logger to Prometheus through
+// github.com/prometheus/client_golang. It is a module of its own so that only programs using it
+// depend on the Prometheus client.
+package promclient
+
+import (
+	"github.com/crit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// promCollector counts written records for Prometheus. It is installed as the logger's
+// log.Metrics.
+type promCollector struct {
+	records *prometheus.CounterVec
+	bytes   *prometheus.CounterVec
+}
+
+// NewCollector returns a Prometheus collector exposing log_records_total and log_bytes_total,
+// both labelled by level, for the records l writes. It replaces any metrics set on l with
+// SetMetrics. Register it like any other collector:
+//
+//	prometheus.MustRegister(promclient.NewCollector(logger))
+func NewCollector(l *log.Logger) prometheus.Collector {
+	c := &promCollector{
+		records: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "log_records_total",
+			Help: "Number of log records written, by level.",
+		}, []string{"level"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "log_bytes_total",
+			Help: "Number of encoded log bytes written, by level.",
+		}, []string{"level"}),
+	}
+
+	l.SetMetrics(c)
+
+	return c
+}
+
+// RecordWritten implements log.Metrics.
+func (c *promCollector) RecordWritten(level log.Level, bytes int) {
+	label := level.String()
+
+	c.records.WithLabelValues(label).Inc()
+	c.bytes.WithLabelValues(label).Add(float64(bytes))
+}
+
+// Describe implements prometheus.Collector.
+func (c *promCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.records.Describe(ch)
+	c.bytes.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *promCollector) Collect(ch chan<- prometheus.Metric) {
+	c.records.Collect(ch)
+	c.bytes.Collect(ch)
+}
@@ -0,0 +1,43 @@
+package promclient
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/crit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewCollector(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := log.NewWithWriter("api", log.InfoLevel, &buf)
+	collector := NewCollector(logger)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	logger.Debug("below the level")
+	logger.Info("started")
+	logger.Info("listening")
+	logger.Error("request failed")
+
+	want := `
+# HELP log_records_total Number of log records written, by level.
+# TYPE log_records_total counter
+log_records_total{level="error"} 1
+log_records_total{level="info"} 2
+`
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "log_records_total"); err != nil {
+		t.Error(err)
+	}
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	if got := testutil.ToFloat64(collector.(*promCollector).bytes.WithLabelValues("error")); got != float64(len(lines[2])) {
+		t.Errorf("log_bytes_total{level=\"error\"} = %v, want %d", got, len(lines[2]))
+	}
+}
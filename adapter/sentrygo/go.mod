@@ -0,0 +1,13 @@
+module github.com/crit/log/adapter/sentrygo
+
+go 1.25.0
+
+require (
+	github.com/crit/log v0.0.0-00010101000000-000000000000
+	github.com/getsentry/sentry-go v0.49.0
+)
+
+require (
+	golang.org/x/sys v0.46.0 // indirect
+	golang.org/x/text v0.39.0 // indirect
+)
@@ -0,0 +1,65 @@
+// Package sentrygo reports records to Sentry through github.com/getsentry/sentry-go. It is a
+// module of its own so that only programs using it depend on the Sentry SDK.
+package sentrygo
+
+import (
+	"strconv"
+
+	"github.com/crit/log"
+	"github.com/getsentry/sentry-go"
+)
+
+// ForSentry returns a hook that reports records at error level and above to Sentry through
+// hub. The message becomes the event message, the record's data a "data" context, and the app
+// name and source its tags. Records below error level are ignored. Install it with AddHook:
+//
+//	logger.AddHook(sentrygo.ForSentry(sentry.CurrentHub()))
+//
+// Hooks run after redaction, so masked fields stay masked in Sentry.
+func ForSentry(hub *sentry.Hub) func(*log.WriteLog) {
+	return func(rec *log.WriteLog) {
+		level, err := log.ParseLevel(rec.Level)
+
+		if err != nil || level < log.ErrorLevel {
+			return
+		}
+
+		event := sentry.NewEvent()
+		event.Level = sentryLevel(level)
+		event.Message = rec.Msg
+		event.Timestamp = rec.Time
+		event.ServerName = rec.Host
+		event.Tags = map[string]string{"app": rec.App}
+
+		if !rec.Src.IsZero() {
+			event.Tags["src"] = rec.Src.Path() + ":" + strconv.Itoa(rec.Src.Line)
+		}
+
+		if len(rec.Data) > 0 {
+			data := make(sentry.Context, len(rec.Data))
+
+			for key, value := range rec.Data {
+				data[key] = value
+			}
+
+			event.Contexts["data"] = data
+		}
+
+		hub.CaptureEvent(event)
+	}
+}
+
+func sentryLevel(level log.Level) sentry.Level {
+	switch level {
+	case log.DebugLevel:
+		return sentry.LevelDebug
+	case log.InfoLevel, log.NoticeLevel:
+		return sentry.LevelInfo
+	case log.WarningLevel:
+		return sentry.LevelWarning
+	case log.ErrorLevel:
+		return sentry.LevelError
+	default:
+		return sentry.LevelFatal
+	}
+}
@@ -0,0 +1,93 @@
+package sentrygo
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crit/log"
+	"github.com/crit/log/testlog"
+	"github.com/getsentry/sentry-go"
+)
+
+// transport records the events a client sends instead of delivering them.
+type transport struct {
+	mutex  sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *transport) Configure(sentry.ClientOptions)        {}
+func (t *transport) Flush(time.Duration) bool              { return true }
+func (t *transport) FlushWithContext(context.Context) bool { return true }
+func (t *transport) Close()                                {}
+
+func (t *transport) SendEvent(event *sentry.Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *transport) sent() []*sentry.Event {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*sentry.Event(nil), t.events...)
+}
+
+func newHub(t *testing.T) (*sentry.Hub, *transport) {
+	t.Helper()
+
+	tr := &transport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:       "https://public@sentry.example.com/1",
+		Transport: tr,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sentry.NewHub(client, sentry.NewScope()), tr
+}
+
+func TestForSentry(t *testing.T) {
+	hub, tr := newHub(t)
+	logger, _ := testlog.New()
+	logger.SetRedactKeys("password")
+	logger.AddHook(ForSentry(hub))
+
+	logger.Info("not reported")
+	logger.Warn("not reported either")
+	logger.With(log.Data{"order": 7, "password": "hunter2"}).Error("payment failed")
+	logger.Critical("database unavailable")
+
+	events := tr.sent()
+
+	if len(events) != 2 {
+		t.Fatalf("sent %d events, want 2", len(events))
+	}
+
+	event := events[0]
+
+	if event.Message != "payment failed" || event.Level != sentry.LevelError {
+		t.Errorf("event = %q at %s, want payment failed at error", event.Message, event.Level)
+	}
+
+	if event.Tags["app"] != logger.AppName() || event.Tags["src"] == "" {
+		t.Errorf("tags = %v", event.Tags)
+	}
+
+	data := event.Contexts["data"]
+
+	if data["order"] != 7 {
+		t.Errorf("data context = %v", data)
+	}
+
+	if data["password"] != "[REDACTED]" {
+		t.Errorf("password reached Sentry as %v", data["password"])
+	}
+
+	if events[1].Level != sentry.LevelFatal {
+		t.Errorf("critical record sent at %s, want fatal", events[1].Level)
+	}
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// logRequest is a wrapper around the logger, reporting its own caller as the source.
+func logRequest(l *Logger, msg string) {
+	l.WithCallerSkip(1).Info(msg)
+}
+
+// nestedLogRequest wraps logRequest, adding its own frame to the skip.
+func nestedLogRequest(l *Logger, msg string) {
+	logRequest(l.WithCallerSkip(1), msg)
+}
+
+// checkSrc fails t unless the single record in buf was reported at line of this file.
+func checkSrc(t *testing.T, buf *bytes.Buffer, line int) {
+	t.Helper()
+
+	records := decodeRecords(t, buf)
+
+	if len(records) != 1 {
+		t.Fatalf("wrote %d records, want 1", len(records))
+	}
+
+	src := records[0].Src
+
+	if filepath.Base(src.File) != "caller_test.go" || src.Line != line {
+		t.Errorf("src = %s:%d, want caller_test.go:%d", src.File, src.Line, line)
+	}
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+
+	_, _, line, _ := runtime.Caller(0)
+	logRequest(l, "skipped one")
+	checkSrc(t, &buf, line+1)
+
+	_, _, line, _ = runtime.Caller(0)
+	nestedLogRequest(l, "skipped two")
+	checkSrc(t, &buf, line+1)
+
+	_, _, line, _ = runtime.Caller(0)
+	l.Info("not skipped")
+	checkSrc(t, &buf, line+1)
+}
+
+// FacadeInfo stands in for a logging facade in a package of its own, with a varying number of
+// frames between its caller and the logger, for TestSetCallerSkipPackages in package log_test.
+func FacadeInfo(l *Logger, msg string, depth int) {
+	if depth > 0 {
+		FacadeInfo(l, msg, depth-1)
+		return
+	}
+
+	l.Info(msg)
+}
+
+func TestPackageFunctionsReportCaller(t *testing.T) {
+	var buf bytes.Buffer
+
+	prev := defaultLogger.Load()
+	defer SetDefault(prev)
+
+	SetDefault(NewWithWriter("app", DebugLevel, &buf))
+
+	funcs := map[string]func(string, ...any){
+		"Debug":     Debug,
+		"Info":      Info,
+		"Notice":    Notice,
+		"Warn":      Warn,
+		"Error":     Error,
+		"Critical":  Critical,
+		"Alert":     Alert,
+		"Emergency": Emergency,
+	}
+
+	for name, fn := range funcs {
+		_, _, line, _ := runtime.Caller(0)
+		fn("via %s", name)
+
+		records := decodeRecords(t, &buf)
+
+		if len(records) != 1 || filepath.Base(records[0].Src.File) != "caller_test.go" || records[0].Src.Line != line+1 {
+			t.Errorf("%s: wrote %v, want one record from caller_test.go:%d", name, records, line+1)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/crit/log"
+)
+
+func TestSetCallerSkipPackages(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := log.NewWithWriter("app", log.DebugLevel, &buf)
+	l.SetCallerSkipPackages("github.com/crit/log")
+
+	for depth := 0; depth < 3; depth++ {
+		_, _, line, _ := runtime.Caller(0)
+		log.FacadeInfo(l, "through the facade", depth)
+
+		var rec log.WriteLog
+
+		if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+
+		buf.Reset()
+
+		if filepath.Base(rec.Src.File) != "callerskip_test.go" || rec.Src.Line != line+1 {
+			t.Errorf("depth %d: src = %s:%d, want callerskip_test.go:%d", depth, rec.Src.File, rec.Src.Line, line+1)
+		}
+	}
+
+	l.SetCallerSkipPackages()
+	log.FacadeInfo(l, "plain caller", 0)
+
+	var rec log.WriteLog
+
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Base(rec.Src.File) != "caller_test.go" {
+		t.Errorf("without packages, src = %s, want the facade in caller_test.go", rec.Src.File)
+	}
+}
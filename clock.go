@@ -0,0 +1,89 @@
+package log
+
+import "time"
+
+// SetClock makes l stamp records with the time returned by now instead of the current time,
+// so tests can pin timestamps. Records are stamped in UTC whatever the location of the
+// returned time. Passing nil restores the system clock. Loggers derived from l afterwards use
+// the same clock.
+func (l *Logger) SetClock(now func() time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.clock = now
+}
+
+// now returns the time to stamp a record with, read from clock if it is set.
+func now(clock func() time.Time) time.Time {
+	if clock == nil {
+		return time.Now().UTC()
+	}
+
+	return clock().UTC()
+}
+
+// SetClockSkewCheck starts comparing the local clock against reference every interval and
+// writes a WarningLevel record whenever the two differ by more than threshold. Skewed clocks
+// silently corrupt timelines assembled from logs of several hosts, so this surfaces the
+// problem where it is visible. The local clock is the one set with SetClock, if any. Calling
+// it again replaces the previous check; a nil reference or a non-positive interval stops
+// checking.
+func (l *Logger) SetClockSkewCheck(reference func() time.Time, threshold time.Duration, interval time.Duration) {
+	l.mutex.Lock()
+
+	if l.skewStop != nil {
+		close(l.skewStop)
+		l.skewStop = nil
+	}
+
+	if reference == nil || interval <= 0 {
+		l.mutex.Unlock()
+		return
+	}
+
+	stop := make(chan struct{})
+	l.skewStop = stop
+
+	l.mutex.Unlock()
+
+	go l.watchClockSkew(reference, threshold, interval, stop)
+}
+
+func (l *Logger) watchClockSkew(reference func() time.Time, threshold, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	l.checkClockSkew(reference, threshold)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.checkClockSkew(reference, threshold)
+		}
+	}
+}
+
+// checkClockSkew writes a warning when the local clock is more than threshold away from
+// reference. A positive skew means the local clock is ahead.
+func (l *Logger) checkClockSkew(reference func() time.Time, threshold time.Duration) {
+	l.mutex.Lock()
+	clock := l.opts.clock
+	l.mutex.Unlock()
+
+	skew := now(clock).Sub(reference())
+	abs := skew
+
+	if abs < 0 {
+		abs = -abs
+	}
+
+	if abs <= threshold {
+		return
+	}
+
+	l.With(Data{
+		"skew":      skew.String(),
+		"threshold": threshold.String(),
+	}).output(2, WarningLevel, "clock skew detected", nil)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestClockSkewCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		skew time.Duration
+		want bool
+	}{
+		{"ahead", 5 * time.Second, true},
+		{"behind", -5 * time.Second, true},
+		{"within threshold", 500 * time.Millisecond, false},
+		{"in step", 0, false},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+
+		l := goldenLogger(&buf)
+		l.SetClock(func() time.Time { return goldenTime.Add(tt.skew) })
+		l.checkClockSkew(func() time.Time { return goldenTime }, time.Second)
+
+		records := decodeRecords(t, &buf)
+
+		if !tt.want {
+			if len(records) != 0 {
+				t.Errorf("%s: wrote %v, want nothing", tt.name, messages(records))
+			}
+
+			continue
+		}
+
+		if len(records) != 1 {
+			t.Fatalf("%s: wrote %v, want one warning", tt.name, messages(records))
+		}
+
+		rec := records[0]
+
+		if rec.Level != WarningLevel.String() || rec.Msg != "clock skew detected" {
+			t.Errorf("%s: wrote %s %q", tt.name, rec.Level, rec.Msg)
+		}
+
+		if rec.Data["skew"] != tt.skew.String() || rec.Data["threshold"] != "1s" {
+			t.Errorf("%s: data = %v", tt.name, rec.Data)
+		}
+	}
+}
+
+func TestSetClockSkewCheckWarns(t *testing.T) {
+	var buf lockedBuffer
+
+	l := NewWithWriter("api", DebugLevel, &buf)
+	l.SetClock(func() time.Time { return goldenTime.Add(time.Minute) })
+	l.SetClockSkewCheck(func() time.Time { return goldenTime }, time.Second, time.Hour)
+	defer l.SetClockSkewCheck(nil, 0, 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !buf.Contains(`"skew":"1m0s"`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no skew warning, got %q", buf.String())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RecordWriter is implemented by writers that want the structured record instead of its
+// JSON encoding. When the logger's writer implements RecordWriter, output hands it the
+// WriteLog directly and skips marshaling.
+type RecordWriter interface {
+	WriteRecord(rec WriteLog) error
+}
+
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+)
+
+var consoleLevelColors = map[string]string{
+	"debug":     "\x1b[90m",
+	"info":      "\x1b[34m",
+	"notice":    "\x1b[36m",
+	"warning":   "\x1b[33m",
+	"error":     "\x1b[31m",
+	"critical":  "\x1b[35m",
+	"alert":     "\x1b[1;31m",
+	"emergency": "\x1b[1;41m",
+}
+
+// ConsoleWriter renders each record as a single human readable line for local development:
+//
+//	2006-01-02T15:04:05 INFO app msg key=value file:line
+//
+// Levels are colorized with ANSI escapes when the destination is a terminal.
+type ConsoleWriter struct {
+	mutex sync.Mutex
+	out   io.Writer
+	color bool
+}
+
+// NewConsoleWriter creates a ConsoleWriter writing to out. Color is enabled only when out is
+// a terminal and the NO_COLOR environment variable is not set.
+func NewConsoleWriter(out io.Writer) *ConsoleWriter {
+	return &ConsoleWriter{
+		out:   out,
+		color: isTerminal(out) && os.Getenv("NO_COLOR") == "",
+	}
+}
+
+// SetColor forces ANSI colors on or off regardless of the destination.
+func (c *ConsoleWriter) SetColor(enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.color = enabled
+}
+
+// Write parses a JSON encoded record and renders it. It allows the ConsoleWriter to sit
+// behind writers that only deal in bytes.
+func (c *ConsoleWriter) Write(p []byte) (n int, err error) {
+	var rec WriteLog
+
+	if err = json.Unmarshal(p, &rec); err != nil {
+		return 0, err
+	}
+
+	if err = c.WriteRecord(rec); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// WriteRecord renders rec as a single line.
+func (c *ConsoleWriter) WriteRecord(rec WriteLog) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var buf bytes.Buffer
+
+	buf.WriteString(rec.Time.Format("2006-01-02T15:04:05"))
+	buf.WriteByte(' ')
+
+	if c.color {
+		buf.WriteString(consoleLevelColors[rec.Level])
+	}
+
+	buf.WriteString(strings.ToUpper(rec.Level))
+
+	if c.color {
+		buf.WriteString(colorReset)
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(rec.App)
+	buf.WriteByte(' ')
+	buf.WriteString(rec.Msg)
+
+	keys := make([]string, 0, len(rec.Data))
+
+	for key := range rec.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(consoleValue(rec.Data[key]))
+	}
+
+	if !rec.Src.IsZero() {
+		buf.WriteByte(' ')
+
+		if c.color {
+			buf.WriteString(colorDim)
+		}
+
+		fmt.Fprintf(&buf, "%s:%d", rec.Src.Path(), rec.Src.Line)
+
+		if c.color {
+			buf.WriteString(colorReset)
+		}
+	}
+
+	buf.WriteByte('\n')
+
+	_, err := c.out.Write(buf.Bytes())
+
+	return err
+}
+
+// consoleValue renders scalars as-is and everything else as compact JSON. Strings containing
+// whitespace or quotes are quoted so each key=value pair stays unambiguous.
+func consoleValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		if strings.ContainsAny(v, " \t\n\"=") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
+
+// isTerminal reports whether w is a character device such as an interactive terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestConsoleWriterLayout(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := NewConsoleWriter(&buf)
+	c.SetColor(false)
+
+	err := c.WriteRecord(WriteLog{
+		Time:  goldenTime,
+		Level: "warning",
+		App:   "api",
+		Msg:   "slow request",
+		Src:   Src{File: "model/user.go", Line: 12},
+		Data:  Data{"user": 42, "path": "/users/42", "note": "took a while"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `2024-03-01T12:30:45 WARNING api slow request note="took a while" path=/users/42 user=42 model/user.go:12` + "\n"
+
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey struct{}
+
+var (
+	contextMutex      sync.RWMutex
+	contextExtractors []func(context.Context) Loggable
+)
+
+// NewContext returns a copy of ctx that carries l. Retrieve it with FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger when there is none,
+// with the data of registered context extractors added. See WithContext.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(contextKey{}).(*Logger)
+
+	if !ok || l == nil {
+		l = Default()
+	}
+
+	return l.WithContext(ctx)
+}
+
+// RegisterContextExtractor adds fn to the functions WithContext uses to pull data, such as
+// trace ids, out of a context. fn may return nil when ctx has nothing to contribute. Register
+// extractors during program initialization.
+func RegisterContextExtractor(fn func(context.Context) Loggable) {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+
+	contextExtractors = append(contextExtractors[:len(contextExtractors):len(contextExtractors)], fn)
+}
+
+// RegisterContextField makes WithContext and FromContext copy the value stored in a context
+// under key into the data of records, as field, for ambient request attributes such as a
+// tenant or user id:
+//
+//	log.RegisterContextField(tenantKey{}, "tenant_id")
+//
+// Contexts without the key contribute nothing. Register fields during program
+// initialization.
+func RegisterContextField(key any, field string) {
+	RegisterContextExtractor(func(ctx context.Context) Loggable {
+		value := ctx.Value(key)
+
+		if value == nil {
+			return nil
+		}
+
+		return Data{field: value}
+	})
+}
+
+// WithContext returns a copy of the logger with the data every registered context extractor
+// finds in ctx. It is a plain copy when no extractor has anything to add.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	contextMutex.RLock()
+	extractors := contextExtractors
+	contextMutex.RUnlock()
+
+	data := make([]Loggable, 0, len(extractors))
+
+	for _, fn := range extractors {
+		if d := fn(ctx); d != nil {
+			data = append(data, d)
+		}
+	}
+
+	return l.With(data...)
+}
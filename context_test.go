@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+)
+
+// tenantKey is the context key registered by TestRegisterContextField; no other test uses it.
+type tenantKey struct{}
+
+// unregisteredKey is a context key that is never registered.
+type unregisteredKey struct{}
+
+// registerTenant registers tenantKey once, so the test can run repeatedly with -count.
+var registerTenant sync.Once
+
+func TestRegisterContextField(t *testing.T) {
+	registerTenant.Do(func() { RegisterContextField(tenantKey{}, "tenant_id") })
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = context.WithValue(ctx, unregisteredKey{}, "secret")
+
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"user": 1})
+
+	got := recordData(t, l.WithContext(ctx))
+
+	if got["tenant_id"] != "acme" || got["user"] != 1.0 {
+		t.Errorf("WithContext data = %v, want tenant_id and user", got)
+	}
+
+	for _, value := range got {
+		if value == "secret" {
+			t.Errorf("a value under an unregistered key was copied: %v", got)
+		}
+	}
+
+	if got := recordData(t, FromContext(NewContext(ctx, l)))["tenant_id"]; got != "acme" {
+		t.Errorf("FromContext tenant_id = %v, want acme", got)
+	}
+
+	if _, ok := recordData(t, l.WithContext(context.Background()))["tenant_id"]; ok {
+		t.Error("a context without the key added tenant_id")
+	}
+}
@@ -0,0 +1,182 @@
+package log
+
+// MergeStrategy decides what happens when With is given a key that already has a value.
+type MergeStrategy int
+
+const (
+	// MergeAppend turns the field into a slice of every value given for the key. This is the
+	// default.
+	MergeAppend MergeStrategy = iota
+	// MergeOverwrite keeps only the most recent value, so a field is never sometimes a scalar
+	// and sometimes an array.
+	MergeOverwrite
+)
+
+// SetMergeStrategy selects how duplicate keys are combined in records from this logger and
+// loggers later derived from it.
+func (l *Logger) SetMergeStrategy(strategy MergeStrategy) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.mergeStrategy = strategy
+}
+
+// fieldSet is one link in the chain of data accumulated by With. A link holds only the fields
+// added by a single With call and is never modified once created, so a child shares its
+// parent's chain instead of copying the parent's whole map. The chain is merged into a
+// record's data when the record is written.
+type fieldSet struct {
+	parent *fieldSet
+	nodes  []map[string]any
+}
+
+// newFieldSet links the fields of data onto parent. Each node is copied so later changes to
+// a caller's map do not leak into records.
+func newFieldSet(parent *fieldSet, data []Loggable) *fieldSet {
+	nodes := make([]map[string]any, 0, len(data))
+
+	for _, node := range data {
+		if node == nil {
+			continue
+		}
+
+		fields := node.Log()
+		snapshot := make(map[string]any, len(fields))
+
+		for key, value := range fields {
+			snapshot[key] = value
+		}
+
+		nodes = append(nodes, snapshot)
+	}
+
+	if len(nodes) == 0 {
+		return parent
+	}
+
+	return &fieldSet{parent: parent, nodes: nodes}
+}
+
+// defaultMaxDepth is how far the data of a record is descended into unless SetMaxDepth says
+// otherwise. Merging always uses it.
+const defaultMaxDepth = 32
+
+// SetMaxDepth bounds how deep the data of records from l may nest. Before a record is
+// redacted, truncated and encoded its data is walked once: maps and slices nested more than n
+// levels down are replaced by "[truncated: max depth]" and a map that contains itself by
+// "[truncated: cycle]", so a self referencing or pathologically deep value cannot hang or
+// crash the logger. The same holds for structs and pointers converted by
+// SetStructKeyConvention. Values shared with the application are copied, not modified. A
+// non-positive n restores the default of 32.
+func (l *Logger) SetMaxDepth(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.maxDepth = n
+}
+
+// depthLimit returns the depth set by SetMaxDepth or the default.
+func (o *options) depthLimit() int {
+	if o.maxDepth <= 0 {
+		return defaultMaxDepth
+	}
+
+	return o.maxDepth
+}
+
+// merge applies the chain to set from the oldest link to the newest.
+func (f *fieldSet) merge(set Data, strategy MergeStrategy) {
+	if f == nil {
+		return
+	}
+
+	f.parent.merge(set, strategy)
+
+	for _, node := range f.nodes {
+		for key, value := range node {
+			mergeField(set, key, value, strategy, 0)
+		}
+	}
+}
+
+// mergeField adds value to set under key. When the key already has a value the field becomes
+// a slice of all values presented for it, or just value under MergeOverwrite. When both values
+// are maps they are merged key by key instead, to a depth of defaultMaxDepth.
+// logger := log.New(...).With(log.Data{"key": "v1"})  => {... "key":"v1" ...}
+// logger.With(log.Data{"key": "v2"})                  => {... "key":["v1","v2"] ...}
+// logger := log.New(...).With(log.Data{"user": log.Data{"id": 1}})
+// logger.With(log.Data{"user": log.Data{"name": "x"}}) => {... "user":{"id":1,"name":"x"} ...}
+func mergeField(set map[string]any, key string, value any, strategy MergeStrategy, depth int) {
+	// do we have a current key already?
+	current, ok := set[key]
+
+	if !ok {
+		// create a new entry
+		set[key] = value
+		return
+	}
+
+	if depth < defaultMaxDepth {
+		if merged, ok := mergeMaps(current, value, strategy, depth+1); ok {
+			set[key] = merged
+			return
+		}
+	}
+
+	if strategy == MergeOverwrite {
+		set[key] = value
+		return
+	}
+
+	// is the current value already a slice?
+	if s, ok := current.([]any); ok {
+		// append to a copy so a caller's slice is never modified
+		set[key] = append(s[:len(s):len(s)], value)
+		return
+	}
+
+	// create a new slice since we have the key already but a new value
+	set[key] = []any{current, value}
+}
+
+// mergeMaps merges value into a copy of current when both are maps. The copy keeps the type
+// of current.
+func mergeMaps(current, value any, strategy MergeStrategy, depth int) (any, bool) {
+	dst, ok := nestedMap(current)
+
+	if !ok {
+		return nil, false
+	}
+
+	src, ok := nestedMap(value)
+
+	if !ok {
+		return nil, false
+	}
+
+	merged := make(map[string]any, len(dst)+len(src))
+
+	for key, v := range dst {
+		merged[key] = v
+	}
+
+	for key, v := range src {
+		mergeField(merged, key, v, strategy, depth)
+	}
+
+	if _, ok := current.(Data); ok {
+		return Data(merged), true
+	}
+
+	return merged, true
+}
+
+// nestedMap returns value as a map when it is one of the map types data may nest.
+func nestedMap(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case Data:
+		return v, true
+	case map[string]any:
+		return v, true
+	}
+
+	return nil, false
+}
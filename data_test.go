@@ -0,0 +1,335 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// eagerWith is With as it was before loggers shared their parent's data: the accumulated
+// fields are copied into a new map on every call. It is the reference the shared chain is
+// checked against.
+func eagerWith(parent map[string]any, data ...Loggable) map[string]any {
+	set := make(map[string]any, len(parent))
+
+	for key, value := range parent {
+		set[key] = value
+	}
+
+	for _, node := range data {
+		for key, value := range node.Log() {
+			mergeField(set, key, value, MergeAppend, 0)
+		}
+	}
+
+	return set
+}
+
+// recordData returns the data of the single record l writes.
+func recordData(t *testing.T, l *Logger) map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	l.SetOutput(&buf)
+	l.SetIncludeSource(false)
+	l.Info("record")
+
+	var rec struct {
+		Data map[string]any `json:"data"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.Data
+}
+
+// normalize round trips value through JSON so values built in memory compare equal to
+// decoded ones.
+func normalize(t *testing.T, value map[string]any) map[string]any {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]any
+
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+}
+
+func TestWithMatchesEagerCopy(t *testing.T) {
+	steps := [][]Loggable{
+		{Data{"user": 1, "role": "admin"}},
+		{Data{"user": 2}, Data{"user": 3}},
+		{Data{"auth": Data{"method": "token"}}},
+		{Data{"auth": Data{"ok": true}, "role": "owner"}},
+		{Data{"user": []any{4, 5}}},
+		{},
+		{Data{"request": "r-1"}},
+	}
+
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+	want := map[string]any{}
+
+	for i, step := range steps {
+		l = l.With(step...)
+		want = eagerWith(want, step...)
+
+		if got := recordData(t, l); !reflect.DeepEqual(got, normalize(t, want)) {
+			t.Fatalf("after step %d got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestWithDuplicateKeysBecomeSlices(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard).
+		With(Data{"key": "v1"}).
+		With(Data{"key": "v2"}).
+		With(Data{"key": "v3"})
+
+	got := recordData(t, l)
+	want := []any{"v1", "v2", "v3"}
+
+	if !reflect.DeepEqual(got["key"], want) {
+		t.Errorf("key = %v, want %v", got["key"], want)
+	}
+}
+
+func TestWithSiblingsDoNotShareValues(t *testing.T) {
+	parent := NewWithWriter("app", DebugLevel, io.Discard).
+		With(Data{"key": "a"}).
+		With(Data{"key": "b"})
+
+	first := parent.With(Data{"key": "c"})
+	second := parent.With(Data{"key": "d"})
+
+	if got := recordData(t, first)["key"]; !reflect.DeepEqual(got, []any{"a", "b", "c"}) {
+		t.Errorf("first child key = %v", got)
+	}
+
+	if got := recordData(t, second)["key"]; !reflect.DeepEqual(got, []any{"a", "b", "d"}) {
+		t.Errorf("second child key = %v", got)
+	}
+
+	if got := recordData(t, parent)["key"]; !reflect.DeepEqual(got, []any{"a", "b"}) {
+		t.Errorf("parent key = %v", got)
+	}
+}
+
+func TestWithCopiesCallerMaps(t *testing.T) {
+	data := Data{"user": 1}
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(data)
+	data["user"] = 2
+
+	if got := recordData(t, l)["user"]; got != float64(1) {
+		t.Errorf("user = %v, want 1", got)
+	}
+}
+
+// BenchmarkWithChain derives loggers through a chain of With calls from a logger that already
+// carries a dozen fields, as request handlers adding context at each layer do.
+func BenchmarkWithChain(b *testing.B) {
+	base := Data{}
+
+	for i := 0; i < 12; i++ {
+		base[fmt.Sprintf("base%d", i)] = i
+	}
+
+	layers := make([]Data, 8)
+
+	for i := range layers {
+		layers[i] = Data{fmt.Sprintf("layer%d", i): i}
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		parent := NewWithWriter("app", DebugLevel, io.Discard).With(base)
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			l := parent
+
+			for _, d := range layers {
+				l = l.With(d)
+			}
+		}
+	})
+
+	// copy is what the chain costs when every With copies the accumulated fields
+	b.Run("copy", func(b *testing.B) {
+		parent := NewWithWriter("app", DebugLevel, io.Discard)
+		parentSet := eagerWith(nil, base)
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			set := parentSet
+
+			for _, d := range layers {
+				_ = parent.clone()
+				set = eagerWith(set, d)
+			}
+		}
+	})
+}
+
+func TestMaxDepthCoversHookData(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+
+	l.AddHook(func(rec *WriteLog) {
+		self := Data{"name": "self"}
+		self["self"] = self
+		rec.Data["hook"] = self
+	})
+
+	got := recordData(t, l)
+	want := map[string]any{"hook": map[string]any{"name": "self", "self": cycleValue}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+}
+
+func TestNilLoggableValueIsNull(t *testing.T) {
+	var fields *Fields
+
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"fields": fields, "user": 1})
+
+	got := recordData(t, l)
+
+	if value, ok := got["fields"]; !ok || value != nil {
+		t.Errorf("fields = %v, want null", value)
+	}
+}
+
+// nest returns value wrapped in n levels of maps under key "n".
+func nest(n int, value any) any {
+	for i := 0; i < n; i++ {
+		value = Data{"n": value}
+	}
+
+	return value
+}
+
+func TestSetMaxDepth(t *testing.T) {
+	tests := []struct {
+		limit int
+		data  Data
+		want  map[string]any
+	}{
+		{2, Data{"a": nest(2, "x")}, map[string]any{"a": map[string]any{"n": map[string]any{"n": "x"}}}},
+		{2, Data{"a": nest(3, "x")}, map[string]any{"a": map[string]any{"n": map[string]any{"n": maxDepthValue}}}},
+		{1, Data{"l": []any{[]any{"x"}, "y"}}, map[string]any{"l": []any{maxDepthValue, "y"}}},
+		// resolving a lazy value counts as a level, so one returning itself cannot loop
+		{2, Data{"a": Lazy(func() any { return nest(2, "x") })}, map[string]any{"a": map[string]any{"n": maxDepthValue}}},
+		{0, Data{"a": nest(defaultMaxDepth, "x")}, normalize(t, map[string]any{"a": nest(defaultMaxDepth, "x")})},
+		{-1, Data{"a": nest(defaultMaxDepth+1, "x")}, normalize(t, map[string]any{"a": nest(defaultMaxDepth, maxDepthValue)})},
+	}
+
+	for _, tt := range tests {
+		l := NewWithWriter("app", DebugLevel, io.Discard).With(tt.data)
+		l.SetMaxDepth(tt.limit)
+
+		if got := recordData(t, l); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("depth %d: data = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestWithFieldMatchesWith(t *testing.T) {
+	base := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"user": 1, "key": "v1"})
+
+	got := recordData(t, base.WithField("request", "r-1").WithField("key", "v2"))
+	want := recordData(t, base.With(Data{"request": "r-1"}).With(Data{"key": "v2"}))
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WithField data = %v, With data = %v", got, want)
+	}
+
+	if !reflect.DeepEqual(got["key"], []any{"v1", "v2"}) || got["user"] != 1.0 || got["request"] != "r-1" {
+		t.Errorf("data = %v, want user and request kept and key merged", got)
+	}
+
+	grouped := recordData(t, base.WithGroup("http").WithField("status", 200))
+
+	if !reflect.DeepEqual(grouped["http"], map[string]any{"status": 200.0}) {
+		t.Errorf("grouped data = %v", grouped)
+	}
+}
+
+func TestWithWorker(t *testing.T) {
+	a, b := NextWorkerID(), NextWorkerID()
+
+	if a == b || a == "" {
+		t.Errorf("NextWorkerID gave %q and %q, want distinct ids", a, b)
+	}
+
+	l := NewWithWriter("app", DebugLevel, io.Discard).WithWorker(a)
+
+	if got := recordData(t, l)["worker"]; got != a {
+		t.Errorf("worker = %v, want %q", got, a)
+	}
+}
+
+func TestWithIfAndWithLevel(t *testing.T) {
+	l := NewWithWriter("app", InfoLevel, io.Discard)
+	query := Data{"query": "q"}
+
+	tests := []struct {
+		name string
+		l    *Logger
+		want bool
+	}{
+		{"WithIf true", l.WithIf(true, query), true},
+		{"WithIf false", l.WithIf(false, query), false},
+		{"WithLevel below", l.WithLevel(DebugLevel, query), false},
+		{"WithLevel at", l.WithLevel(InfoLevel, query), true},
+		{"WithLevel above", l.WithLevel(ErrorLevel, query), true},
+	}
+
+	for _, tt := range tests {
+		if _, got := recordData(t, tt.l)["query"]; got != tt.want {
+			t.Errorf("%s: query attached = %v, want %v", tt.name, got, tt.want)
+		}
+
+		if (tt.l == l) == tt.want {
+			t.Errorf("%s: returned the logger itself = %v", tt.name, tt.l == l)
+		}
+	}
+
+	l.SetLevel(DebugLevel)
+
+	if _, ok := recordData(t, l.WithLevel(DebugLevel, query))["query"]; !ok {
+		t.Error("WithLevel(debug) did not attach data once debug was enabled")
+	}
+}
+
+func TestSetBaseFields(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+	l.SetBaseFields(Data{"version": "1.0", "region": "eu"}, Data{"version": "1.1"})
+
+	child := l.With(Data{"version": "dev", "user": 1})
+	want := map[string]any{"version": "1.1", "region": "eu", "user": 1.0}
+
+	if got := recordData(t, child); !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+
+	l.SetBaseFields()
+
+	if got := recordData(t, l.With(Data{"user": 1})); !reflect.DeepEqual(got, map[string]any{"user": 1.0}) {
+		t.Errorf("after removing the base fields, data = %v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// DatadogFormatter encodes records with the reserved attributes of Datadog's log pipeline:
+// "status" for the level, "service" for the app, "message", "timestamp" and "host". Data
+// fields are written as top level attributes, and Env and Version, when set, are sent as
+// ddtags. Use it with SetFormatter.
+//
+// Records carrying trace_id and span_id as hex strings, as added by the OpenTelemetry
+// context extractor, also get dd.trace_id and dd.span_id in the decimal form Datadog uses to
+// correlate logs with traces.
+type DatadogFormatter struct {
+	// Env is sent as the env tag.
+	Env string
+	// Version is sent as the version tag.
+	Version string
+}
+
+// Format implements Formatter.
+func (f DatadogFormatter) Format(rec WriteLog) ([]byte, error) {
+	doc := make(map[string]any, 10+len(rec.Data))
+
+	for key, value := range rec.Data {
+		doc[key] = value
+	}
+
+	if traceID, ok := datadogID(rec.Data["trace_id"]); ok {
+		if spanID, ok := datadogID(rec.Data["span_id"]); ok {
+			doc["dd.trace_id"] = traceID
+			doc["dd.span_id"] = spanID
+		}
+	}
+
+	// core fields are written last so data can never shadow them
+	doc["timestamp"] = rec.Time.UTC()
+	doc["status"] = rec.Level
+	doc["message"] = rec.Msg
+	doc["service"] = rec.App
+
+	if tags := f.tags(); tags != "" {
+		doc["ddtags"] = tags
+	}
+
+	if !rec.Src.IsZero() {
+		doc["src"] = rec.Src
+	}
+
+	if rec.Host != "" {
+		doc["host"] = rec.Host
+	}
+
+	if rec.PID != 0 {
+		doc["pid"] = rec.PID
+	}
+
+	return json.Marshal(doc)
+}
+
+func (f DatadogFormatter) tags() string {
+	tags := make([]string, 0, 2)
+
+	if f.Env != "" {
+		tags = append(tags, "env:"+f.Env)
+	}
+
+	if f.Version != "" {
+		tags = append(tags, "version:"+f.Version)
+	}
+
+	return strings.Join(tags, ",")
+}
+
+// datadogID converts a hex trace or span id to the decimal form Datadog expects, keeping the
+// low 64 bits of 128 bit trace ids.
+func datadogID(value any) (string, bool) {
+	id, ok := value.(string)
+
+	if !ok || id == "" {
+		return "", false
+	}
+
+	if len(id) > 16 {
+		id = id[len(id)-16:]
+	}
+
+	n, err := strconv.ParseUint(id, 16, 64)
+
+	if err != nil {
+		return "", false
+	}
+
+	return strconv.FormatUint(n, 10), true
+}
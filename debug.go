@@ -0,0 +1,156 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// goroutineChunkBytes bounds the size of the stack text carried by a single record.
+const goroutineChunkBytes = 32 << 10
+
+// DumpGoroutines captures the stacks of all goroutines and writes them at WarningLevel. This
+// is intended for diagnosing hangs and deadlocks in a running process. Large dumps are split
+// across several records, each carrying its part number, so no single line grows unbounded
+// and each part fits within the SetMaxRecordBytes limit. The parts bypass sampling, rate
+// limiting and SetDedup, so a dump is never cut short.
+func (l *Logger) DumpGoroutines() {
+	buf := make([]byte, 64<<10)
+
+	for {
+		n := runtime.Stack(buf, true)
+
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	chunks := splitChunks(buf, l.goroutineChunkSize())
+	diag := l.diagnostic()
+
+	for i, chunk := range chunks {
+		diag.With(Data{
+			"stack": string(chunk),
+			"part":  i + 1,
+			"parts": len(chunks),
+		}).output(2, WarningLevel, "goroutine dump", nil)
+	}
+}
+
+// diagnostic returns a copy of the logger for records that describe the state of the process
+// and are worthless unless every one is written, such as the parts of a goroutine dump,
+// heartbeats and recovered panics. They bypass sampling, rate limiting and SetDedup, as for
+// Always.
+func (l *Logger) diagnostic() *Logger {
+	return l.Always()
+}
+
+// Recover logs a panic as a CriticalLevel record carrying the panic value and the stack of the
+// panicking goroutine, then lets the goroutine carry on as if the panic had not happened. It
+// only works when deferred directly:
+//
+//	defer logger.Recover()
+//
+// The record's src is where the panic happened rather than where Recover was deferred. Like
+// the parts of DumpGoroutines, panic records bypass sampling, rate limiting and SetDedup, so
+// a crash is never dropped for looking like the records before it.
+func (l *Logger) Recover() {
+	if r := recover(); r != nil {
+		l.logPanic(r)
+	}
+}
+
+// RecoverRepanic is Recover for code that must still crash: after logging it panics again
+// with the same value. Deferred at the top of main it leaves a record of the crash without
+// changing how the program ends.
+func (l *Logger) RecoverRepanic() {
+	if r := recover(); r != nil {
+		l.logPanic(r)
+		panic(r)
+	}
+}
+
+func (l *Logger) logPanic(r any) {
+	if !l.Enabled(CriticalLevel) {
+		return
+	}
+
+	l.diagnostic().emit(2, CriticalLevel, WriteLog{
+		Msg: "panic recovered",
+		Data: Data{
+			"panic": fmt.Sprint(r),
+			"stack": string(debug.Stack()),
+		},
+		Src: panicSource(),
+	})
+}
+
+// panicSource returns the location of the innermost frame outside the runtime and this
+// package, which for a deferred Recover is the code that panicked.
+func panicSource() Src {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, "runtime.") && !strings.HasPrefix(frame.Function, "github.com/crit/log.") {
+			src := Src{File: frame.File, Line: frame.Line}
+			src.TruncateFile()
+
+			return src
+		}
+
+		if !more {
+			return Src{File: "???"}
+		}
+	}
+}
+
+// goroutineChunkSize keeps each dump record within the logger's record size limit, leaving
+// room for the rest of the record and for escaping of the stack text.
+func (l *Logger) goroutineChunkSize() int {
+	l.mutex.Lock()
+	limit := l.opts.maxRecordBytes
+	l.mutex.Unlock()
+
+	size := goroutineChunkBytes
+
+	if limit > 0 && limit/2 < size {
+		size = limit / 2
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	return size
+}
+
+// splitChunks splits p into pieces of at most size bytes, preferring to break after a
+// newline so stack frames are not cut in half.
+func splitChunks(p []byte, size int) [][]byte {
+	var chunks [][]byte
+
+	for len(p) > size {
+		cut := bytes.LastIndexByte(p[:size], '\n') + 1
+
+		if cut <= 0 {
+			cut = size
+		}
+
+		chunks = append(chunks, p[:cut])
+		p = p[cut:]
+	}
+
+	if len(p) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, p)
+	}
+
+	return chunks
+}
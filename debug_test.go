@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDumpGoroutinesWritesEveryPart(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetMaxRecordBytes(512)
+	l.SetSampler(1, 0)
+	l.SetRateLimit(1)
+	l.SetDedup(time.Minute)
+
+	l.DumpGoroutines()
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+
+	var last WriteLog
+
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil {
+		t.Fatal(err)
+	}
+
+	parts, _ := last.Data["parts"].(float64)
+
+	if parts < 2 {
+		t.Fatalf("dump was written in %v parts, want several", parts)
+	}
+
+	if len(lines) != int(parts) {
+		t.Fatalf("wrote %d records for a dump of %v parts", len(lines), parts)
+	}
+}
+
+func TestRecoverWritesEveryPanic(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetSampler(1, 0)
+	l.SetRateLimit(1)
+	l.SetDedup(time.Minute)
+
+	for i := 0; i < 5; i++ {
+		func() {
+			defer l.Recover()
+			panic("boom")
+		}()
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+
+	if len(lines) != 5 {
+		t.Fatalf("wrote %d records for 5 panics:\n%s", len(lines), buf.Bytes())
+	}
+
+	for _, line := range lines {
+		var rec WriteLog
+
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatal(err)
+		}
+
+		if rec.Msg != "panic recovered" || rec.Data["panic"] != "boom" {
+			t.Errorf("unexpected record %s", line)
+		}
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	value := errors.New("boom")
+
+	repanicked := func() (r any) {
+		defer func() { r = recover() }()
+
+		func() {
+			defer l.RecoverRepanic()
+			panic(value)
+		}()
+
+		return nil
+	}()
+
+	if repanicked != value {
+		t.Errorf("panic value after RecoverRepanic = %v, want the original", repanicked)
+	}
+
+	var rec WriteLog
+
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Level != "critical" || rec.Msg != "panic recovered" || rec.Data["panic"] != "boom" {
+		t.Errorf("unexpected record %s", buf.Bytes())
+	}
+
+	buf.Reset()
+
+	func() {
+		defer l.RecoverRepanic()
+	}()
+
+	if buf.Len() != 0 {
+		t.Errorf("wrote %s without a panic", buf.Bytes())
+	}
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// SetDedup coalesces identical records. Once a record has been written, records with the same
+// level and message that follow it within window are dropped and counted. When the window
+// closes, or a different record is written first, a single record with that level and
+// message and a repeated field holding the count is written in their place, so a flapping
+// dependency logs two lines instead of thousands. Whether a record falls within the window is
+// judged by the clock set by SetClock; a window with duplicates still closes on its own after
+// window has passed in real time. Only the latest record is tracked, which keeps memory
+// constant. The state is shared by loggers derived from this one. Records exempted with
+// Always, and Alert and Emergency records, are never coalesced. A non-positive window
+// disables deduplication.
+func (l *Logger) SetDedup(window time.Duration) {
+	var d *deduper
+
+	if window > 0 {
+		d = &deduper{window: window}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.deduper = d
+}
+
+type dedupKey struct {
+	level Level
+	msg   string
+}
+
+// deduper remembers the latest record written and how many duplicates of it were dropped.
+type deduper struct {
+	mutex    sync.Mutex
+	window   time.Duration
+	key      dedupKey
+	seen     bool
+	since    time.Time
+	repeated int
+	logger   *Logger
+	timer    *time.Timer
+}
+
+// dedupSummary is a pending "repeated" record.
+type dedupSummary struct {
+	logger   *Logger
+	key      dedupKey
+	repeated int
+}
+
+func (s dedupSummary) write() {
+	if s.repeated > 0 {
+		s.logger.writeInternal(s.key.level, s.key.msg, Data{"repeated": s.repeated})
+	}
+}
+
+// allow reports whether a record should be written. It also returns the summary of the
+// previous record's duplicates when the record ends them.
+func (d *deduper) allow(l *Logger, level Level, msg string, now time.Time) (bool, dedupSummary) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	key := dedupKey{level: level, msg: msg}
+
+	if d.seen && d.key == key && now.Sub(d.since) < d.window {
+		d.repeated++
+		d.logger = l
+
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.window-now.Sub(d.since), d.expire)
+		}
+
+		return false, dedupSummary{}
+	}
+
+	summary := d.reset()
+
+	d.key = key
+	d.seen = true
+	d.since = now
+
+	return true, summary
+}
+
+// expire ends the window of the latest record, writing the summary of its duplicates.
+func (d *deduper) expire() {
+	d.mutex.Lock()
+	summary := d.reset()
+	d.seen = false
+	d.mutex.Unlock()
+
+	summary.write()
+}
+
+// reset clears the duplicate count and returns its summary. The caller holds the mutex.
+func (d *deduper) reset() dedupSummary {
+	summary := dedupSummary{logger: d.logger, key: d.key, repeated: d.repeated}
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	d.repeated = 0
+	d.logger = nil
+
+	return summary
+}
+
+// deduped consults the deduper, if any, and writes the summary of a finished run of
+// duplicates.
+func (l *Logger) deduped(level Level, msg string) bool {
+	l.mutex.Lock()
+	d := l.opts.deduper
+	clock := l.opts.clock
+	l.mutex.Unlock()
+
+	if d == nil {
+		return true
+	}
+
+	ok, summary := d.allow(l, level, msg, now(clock))
+	summary.write()
+
+	return ok
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer for records written from other goroutines.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Contains(sub string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return bytes.Contains(b.buf.Bytes(), []byte(sub))
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.buf.String()
+}
+
+func TestDedupCollapsesRepeats(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	l := goldenLogger(&buf)
+	l.SetClock(clock.Now)
+	l.SetDedup(time.Hour)
+
+	for i := 0; i < 5; i++ {
+		l.Error("upstream down")
+	}
+
+	if got := decodeRecords(t, &buf); len(got) != 1 || got[0].Data["repeated"] != nil {
+		t.Fatalf("5 identical records wrote %+v, want the first alone", got)
+	}
+
+	// a different record ends the run
+	l.Info("recovered")
+
+	got := decodeRecords(t, &buf)
+
+	if !reflect.DeepEqual(messages(got), []string{"upstream down", "recovered"}) {
+		t.Fatalf("wrote %v, want the summary and the new record", messages(got))
+	}
+
+	if got[0].Level != "error" || got[0].Data["repeated"] != float64(4) {
+		t.Errorf("summary = %+v, want an error record repeated 4 times", got[0])
+	}
+
+	// the window closing ends the run too
+	l.Info("recovered")
+	l.Info("recovered")
+	clock.Advance(2 * time.Hour)
+	l.Info("recovered")
+
+	got = decodeRecords(t, &buf)
+
+	if len(got) != 2 || got[0].Data["repeated"] != float64(2) || got[1].Data["repeated"] != nil {
+		t.Errorf("after the window wrote %+v, want a summary of 2 then the record", got)
+	}
+}
+
+func TestDedupKeysOnLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetClock(newFakeClock().Now)
+	l.SetDedup(time.Hour)
+
+	l.Info("retrying")
+	l.Warn("retrying")
+
+	if got := decodeRecords(t, &buf); len(got) != 2 {
+		t.Errorf("the same message at two levels wrote %d records, want 2", len(got))
+	}
+}
+
+func TestDedupWindowExpires(t *testing.T) {
+	var buf lockedBuffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetDedup(20 * time.Millisecond)
+
+	l.Info("flap")
+	l.Info("flap")
+	l.Info("flap")
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !buf.Contains(`"repeated":2`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no summary after the window closed: %s", buf.String())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
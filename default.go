@@ -0,0 +1,83 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var defaultLogger atomic.Pointer[Logger]
+
+// Default returns the logger used by the package level functions. Unless replaced with
+// SetDefault it is named after the executable and reads its level from LOG_LEVEL, like
+// SetupLogger.
+func Default() *Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+
+	name := "app"
+
+	if len(os.Args) > 0 {
+		name = filepath.Base(os.Args[0])
+	}
+
+	defaultLogger.CompareAndSwap(nil, New(name, ToLevel(os.Getenv("LOG_LEVEL"))))
+
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the logger used by the package level functions. Passing nil restores
+// the environment based default.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// The package level functions call output directly, rather than through the Logger methods,
+// so the captured source is the caller's and not this file.
+
+// Debug records detailed debug information using the default logger.
+func Debug(msg string, args ...any) {
+	Default().output(2, DebugLevel, msg, args)
+}
+
+// Info records interesting events using the default logger.
+func Info(msg string, args ...any) {
+	Default().output(2, InfoLevel, msg, args)
+}
+
+// Notice records normal but significant events using the default logger.
+func Notice(msg string, args ...any) {
+	Default().output(2, NoticeLevel, msg, args)
+}
+
+// Warn records exceptional occurrences that are not errors using the default logger.
+func Warn(msg string, args ...any) {
+	Default().output(2, WarningLevel, msg, args)
+}
+
+// Error records runtime errors using the default logger.
+func Error(msg string, args ...any) {
+	Default().output(2, ErrorLevel, msg, args)
+}
+
+// Critical records critical conditions using the default logger.
+func Critical(msg string, args ...any) {
+	Default().output(2, CriticalLevel, msg, args)
+}
+
+// Alert records exceptions where action must be taken immediately using the default logger.
+func Alert(msg string, args ...any) {
+	Default().output(2, AlertLevel, msg, args)
+}
+
+// Emergency records instances where the system is unusable using the default logger.
+func Emergency(msg string, args ...any) {
+	Default().output(2, EmergencyLevel, msg, args)
+}
+
+// Fatal writes an emergency log using the default logger and then calls os.Exit(1).
+func Fatal(msg string, args ...any) {
+	Default().output(2, EmergencyLevel, msg, args)
+	os.Exit(1)
+}
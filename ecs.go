@@ -0,0 +1,68 @@
+package log
+
+import "encoding/json"
+
+// ecsVersion is the Elastic Common Schema version the ECSFormatter targets.
+const ecsVersion = "8.11.0"
+
+// ecsTimeFormat is ISO8601 with millisecond precision, the resolution Elasticsearch stores.
+const ecsTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ECSFormatter encodes records using Elastic Common Schema field names: "@timestamp",
+// "log.level", "message", "service.name" and "log.origin.*". Data is placed under "labels"
+// unless Flatten is set. Use it with SetFormatter.
+type ECSFormatter struct {
+	// Flatten writes data fields as top level dotted keys instead of nesting them under labels.
+	Flatten bool
+}
+
+// Format implements Formatter.
+func (f ECSFormatter) Format(rec WriteLog) ([]byte, error) {
+	doc := make(map[string]any, 8+len(rec.Data))
+
+	if len(rec.Data) > 0 {
+		if f.Flatten {
+			flattenData(doc, "", rec.Data)
+		} else {
+			doc["labels"] = rec.Data
+		}
+	}
+
+	// core fields are written last so data can never shadow them
+	doc["@timestamp"] = rec.Time.UTC().Format(ecsTimeFormat)
+	doc["log.level"] = rec.Level
+	doc["message"] = rec.Msg
+	doc["ecs.version"] = ecsVersion
+	doc["service.name"] = rec.App
+
+	if !rec.Src.IsZero() {
+		doc["log.origin.file.name"] = rec.Src.Path()
+		doc["log.origin.file.line"] = rec.Src.Line
+	}
+
+	if rec.Host != "" {
+		doc["host.name"] = rec.Host
+	}
+
+	if rec.PID != 0 {
+		doc["process.pid"] = rec.PID
+	}
+
+	return json.Marshal(doc)
+}
+
+// flattenData copies data into dst, joining the keys of nested maps with dots.
+//
+// {"user": {"id": 1}} => {"user.id": 1}
+func flattenData(dst map[string]any, prefix string, data map[string]any) {
+	for key, value := range data {
+		switch v := value.(type) {
+		case Data:
+			flattenData(dst, prefix+key+".", v)
+		case map[string]any:
+			flattenData(dst, prefix+key+".", v)
+		default:
+			dst[prefix+key] = value
+		}
+	}
+}
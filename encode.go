@@ -0,0 +1,378 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// MarshalJSON encodes the record in the canonical order without reflecting over the struct.
+// Core fields and the common scalar, map and slice values in Data are written directly; any
+// other value falls back to encoding/json, so the bytes are those encoding/json would give,
+// except that a record without a source omits src instead of writing an empty one.
+func (w WriteLog) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := w.encodeJSON(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeJSON appends the JSON encoding of w to buf. On error buf holds a partial record.
+func (w WriteLog) encodeJSON(buf *bytes.Buffer) error {
+	var scratch [64]byte
+
+	if year := w.Time.Year(); year < 0 || year > 9999 {
+		// let time report the out of range year exactly as encoding/json would
+		_, err := w.Time.MarshalJSON()
+		return err
+	}
+
+	buf.WriteString(`{"time":"`)
+	buf.Write(w.Time.AppendFormat(scratch[:0], time.RFC3339Nano))
+	buf.WriteString(`","level":`)
+	writeJSONString(buf, w.Level)
+
+	if w.LevelNum != nil {
+		buf.WriteString(`,"level_num":`)
+		buf.Write(strconv.AppendInt(scratch[:0], int64(*w.LevelNum), 10))
+	}
+
+	buf.WriteString(`,"app":`)
+	writeJSONString(buf, w.App)
+
+	if w.Host != "" {
+		buf.WriteString(`,"host":`)
+		writeJSONString(buf, w.Host)
+	}
+
+	if w.PID != 0 {
+		buf.WriteString(`,"pid":`)
+		buf.Write(strconv.AppendInt(scratch[:0], int64(w.PID), 10))
+	}
+
+	buf.WriteString(`,"msg":`)
+	writeJSONString(buf, w.Msg)
+
+	if !w.Src.IsZero() {
+		buf.WriteString(`,"src":{`)
+
+		if w.Src.Package != "" {
+			buf.WriteString(`"package":`)
+			writeJSONString(buf, w.Src.Package)
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(`"file":`)
+		writeJSONString(buf, w.Src.File)
+		buf.WriteString(`,"line":`)
+		buf.Write(strconv.AppendInt(scratch[:0], int64(w.Src.Line), 10))
+		buf.WriteByte('}')
+	}
+
+	if len(w.Data) > 0 {
+		buf.WriteString(`,"data":`)
+
+		if err := writeJSONMap(buf, w.Data); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// startDetectingCyclesAfter is how deep maps and slices nest before the encoder starts
+// tracking them, the same threshold encoding/json uses, so the finite data of ordinary
+// records costs nothing for the check.
+const startDetectingCyclesAfter = 1000
+
+// jsonPath holds the maps and slices the encoder is inside of, so data that contains itself
+// fails with the error encoding/json gives instead of recursing until the stack overflows.
+type jsonPath struct {
+	depth int
+	seen  map[visit]bool
+}
+
+// enter records that the encoder descends into value, a map or slice, and fails when value
+// is already being encoded further up.
+func (p *jsonPath) enter(value any) error {
+	p.depth++
+
+	if p.depth <= startDetectingCyclesAfter {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	key := visit{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+
+	if p.seen[key] {
+		return &json.UnsupportedValueError{Value: v, Str: fmt.Sprintf("encountered a cycle via %s", v.Type())}
+	}
+
+	if p.seen == nil {
+		p.seen = map[visit]bool{}
+	}
+
+	p.seen[key] = true
+
+	return nil
+}
+
+// leave undoes the matching enter.
+func (p *jsonPath) leave(value any) {
+	if p.depth > startDetectingCyclesAfter {
+		v := reflect.ValueOf(value)
+		delete(p.seen, visit{ptr: v.Pointer(), typ: v.Type(), len: v.Len()})
+	}
+
+	p.depth--
+}
+
+// writeJSONValue encodes value as encoding/json would, writing the common types directly.
+func writeJSONValue(buf *bytes.Buffer, value any) error {
+	var path jsonPath
+
+	return path.value(buf, value)
+}
+
+// writeJSONMap encodes m with its keys sorted, as encoding/json does.
+func writeJSONMap(buf *bytes.Buffer, m map[string]any) error {
+	var path jsonPath
+
+	return path.mapValue(buf, m, m)
+}
+
+func (p *jsonPath) value(buf *bytes.Buffer, value any) error {
+	var scratch [32]byte
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], v))
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int8:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int16:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint8:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint16:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint32:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], v, 10))
+	case float32:
+		return writeJSONFloat(buf, float64(v), 32, value)
+	case float64:
+		return writeJSONFloat(buf, v, 64, value)
+	case Data:
+		return p.mapValue(buf, v, value)
+	case map[string]any:
+		return p.mapValue(buf, v, value)
+	case []any:
+		if v == nil {
+			buf.WriteString("null")
+			return nil
+		}
+
+		if err := p.enter(value); err != nil {
+			return err
+		}
+
+		defer p.leave(value)
+
+		buf.WriteByte('[')
+
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := p.value(buf, item); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	default:
+		data, err := json.Marshal(v)
+
+		if err != nil {
+			return err
+		}
+
+		buf.Write(data)
+	}
+
+	return nil
+}
+
+// mapValue encodes m, which value holds, with its keys sorted.
+func (p *jsonPath) mapValue(buf *bytes.Buffer, m map[string]any, value any) error {
+	if m == nil {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if err := p.enter(value); err != nil {
+		return err
+	}
+
+	defer p.leave(value)
+
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	buf.WriteByte('{')
+
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		writeJSONString(buf, key)
+		buf.WriteByte(':')
+
+		if err := p.value(buf, m[key]); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// writeJSONFloat formats f the way encoding/json does: shortest representation, with
+// exponents only for very small or large magnitudes. NaN and infinities are left to
+// encoding/json so the error is the same.
+func writeJSONFloat(buf *bytes.Buffer, f float64, bits int, value any) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		_, err := json.Marshal(value)
+		return err
+	}
+
+	var scratch [32]byte
+
+	format := byte('f')
+
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	b := strconv.AppendFloat(scratch[:0], f, format, -1, bits)
+
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+
+	buf.Write(b)
+
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a JSON string with the escaping encoding/json applies by
+// default, including the HTML characters <, > and &.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	start := 0
+
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\b':
+				buf.WriteString(`\b`)
+			case '\f':
+				buf.WriteString(`\f`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xF])
+			}
+
+			i++
+			start = i
+
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		// invalid bytes become the replacement character
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+
+			continue
+		}
+
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+
+			continue
+		}
+
+		i += size
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,250 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// reflectRecord is the schema of WriteLog as encoding/json writes it by reflection, with src
+// omitted when empty as MarshalJSON does.
+type reflectRecord struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	LevelNum *int      `json:"level_num,omitempty"`
+	App      string    `json:"app"`
+	Host     string    `json:"host,omitempty"`
+	PID      int       `json:"pid,omitempty"`
+	Msg      string    `json:"msg"`
+	Src      *Src      `json:"src,omitempty"`
+	Data     Data      `json:"data,omitempty"`
+}
+
+// reflectJSON encodes w with encoding/json through reflectRecord.
+func reflectJSON(w WriteLog) ([]byte, error) {
+	rec := reflectRecord{
+		Time:     w.Time,
+		Level:    w.Level,
+		LevelNum: w.LevelNum,
+		App:      w.App,
+		Host:     w.Host,
+		PID:      w.PID,
+		Msg:      w.Msg,
+		Data:     w.Data,
+	}
+
+	if !w.Src.IsZero() {
+		rec.Src = &w.Src
+	}
+
+	return json.Marshal(rec)
+}
+
+type point struct {
+	X, Y int
+}
+
+// trickyRecords are records whose encoding exercises every branch of the hand written
+// encoder and the fallback.
+func trickyRecords() []WriteLog {
+	num := 13
+	empty := ""
+
+	return []WriteLog{
+		{},
+		{Time: goldenTime, Level: "info", App: "api", Msg: "plain"},
+		{
+			Time:     goldenTime.In(time.FixedZone("", -7*3600)),
+			Level:    "warning",
+			LevelNum: &num,
+			App:      "api",
+			Host:     "web-1",
+			PID:      4242,
+			Msg:      "with everything",
+			Src:      Src{Package: "model", File: "user.go", Line: 12},
+			Data:     Data{"user": 42},
+		},
+		{
+			Msg: "quote \" backslash \\ <html> & tab\t nl\n cr\r \b \f \x00 \x1f \x7f",
+			Src: Src{File: `C:\dir\file.go`},
+			Data: Data{
+				"invalid":   "bad \xff\xfe bytes",
+				"separator": "line\u2028paragraph\u2029",
+				"unicode":   "ünïcödé ☃ 😀",
+				"<key>":     "&",
+			},
+		},
+		{
+			Msg: "numbers",
+			Data: Data{
+				"int":     -1,
+				"int8":    int8(math.MinInt8),
+				"int16":   int16(math.MaxInt16),
+				"int32":   int32(math.MinInt32),
+				"int64":   int64(math.MaxInt64),
+				"uint":    uint(7),
+				"uint8":   uint8(255),
+				"uint16":  uint16(math.MaxUint16),
+				"uint32":  uint32(math.MaxUint32),
+				"uint64":  uint64(math.MaxUint64),
+				"zero":    0.0,
+				"neg":     math.Copysign(0, -1),
+				"tenth":   0.1,
+				"small":   1e-7,
+				"edge":    1e-6,
+				"big":     1e21,
+				"under":   1e20,
+				"huge":    math.MaxFloat64,
+				"tiny":    math.SmallestNonzeroFloat64,
+				"f32":     float32(0.1),
+				"f32big":  float32(1e21),
+				"f32tiny": float32(1e-7),
+				"f32max":  float32(math.MaxFloat32),
+			},
+		},
+		{
+			Msg: "composites",
+			Data: Data{
+				"nested":   Data{"a": Data{"b": []any{1, "two", nil, Data{}}}},
+				"map":      map[string]any{"z": 1, "a": 2},
+				"nilmap":   map[string]any(nil),
+				"nildata":  Data(nil),
+				"nilslice": []any(nil),
+				"empty":    []any{},
+				"strings":  []string{"a", "b"},
+				"struct":   point{1, 2},
+				"pointer":  &empty,
+				"time":     goldenTime,
+				"duration": time.Second,
+				"error":    errors.New("boom"),
+				"raw":      json.RawMessage(`{"raw":true}`),
+				"bytes":    []byte("hi"),
+				"intmap":   map[int]string{2: "b", 1: "a"},
+				"nil":      nil,
+			},
+		},
+	}
+}
+
+func TestMarshalJSONMatchesEncodingJSON(t *testing.T) {
+	for i, rec := range trickyRecords() {
+		got, err := rec.MarshalJSON()
+
+		if err != nil {
+			t.Errorf("record %d: %v", i, err)
+			continue
+		}
+
+		want, err := reflectJSON(rec)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("record %d\ngot:  %s\nwant: %s", i, got, want)
+		}
+
+		if !json.Valid(got) {
+			t.Errorf("record %d is not valid JSON: %s", i, got)
+		}
+	}
+}
+
+func TestMarshalJSONGolden(t *testing.T) {
+	var buf bytes.Buffer
+
+	for _, rec := range trickyRecords() {
+		data, err := rec.MarshalJSON()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	checkGolden(t, "marshal.golden", buf.Bytes())
+}
+
+func TestMarshalJSONErrors(t *testing.T) {
+	records := map[string]WriteLog{
+		"NaN":         {Data: Data{"ratio": math.NaN()}},
+		"infinity":    {Data: Data{"nested": []any{math.Inf(1)}}},
+		"float32 NaN": {Data: Data{"ratio": float32(math.NaN())}},
+		"year":        {Time: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"channel":     {Data: Data{"ch": make(chan int)}},
+	}
+
+	for name, rec := range records {
+		_, err := rec.MarshalJSON()
+		_, want := reflectJSON(rec)
+
+		if err == nil || want == nil {
+			t.Errorf("%s: MarshalJSON error %v, encoding/json error %v", name, err, want)
+		}
+	}
+}
+
+// BenchmarkMarshalJSON encodes a typical record with MarshalJSON and, for comparison, with
+// encoding/json reflecting over the struct.
+func BenchmarkMarshalJSON(b *testing.B) {
+	rec := WriteLog{
+		Time:  goldenTime,
+		Level: "info",
+		App:   "api",
+		Host:  "web-1",
+		PID:   4242,
+		Msg:   "request done",
+		Src:   Src{File: "handler/users.go", Line: 87},
+		Data:  Data{"user": 42, "path": "/users/42", "cached": true, "latency_ms": 1.5},
+	}
+
+	b.Run("encoder", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := rec.MarshalJSON(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(plainRecord(rec)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestMarshalJSONCycles(t *testing.T) {
+	self := Data{"name": "self"}
+	self["self"] = self
+
+	list := []any{"item", nil}
+	list[1] = list
+
+	records := map[string]WriteLog{
+		"map":   {Data: Data{"loop": self}},
+		"slice": {Data: Data{"loop": list}},
+		"data":  {Data: self},
+	}
+
+	for name, rec := range records {
+		_, err := json.Marshal(rec)
+
+		var unsupported *json.UnsupportedValueError
+
+		if !errors.As(err, &unsupported) {
+			t.Errorf("%s: error %v, want a json.UnsupportedValueError", name, err)
+		}
+	}
+}
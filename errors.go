@@ -0,0 +1,60 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrEmptyMessage is reported to the error handler for each record dropped by
+// SetRequireMessage.
+var ErrEmptyMessage = errors.New("log: record without a message dropped")
+
+// SetErrorHandler installs fn to be called whenever a record cannot be encoded or the writer
+// returns an error, so dropped logs do not go unnoticed. The default handler prints the error
+// to os.Stderr. Passing nil restores the default.
+func (l *Logger) SetErrorHandler(fn func(error)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.errorHandler = fn
+}
+
+// handleError reports err to the configured error handler.
+func (o *options) handleError(err error) {
+	if o.errorHandler != nil {
+		o.errorHandler(err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "log: %v\n", err)
+}
+
+// SetRequireMessage makes l drop records whose message is empty, such as those from an
+// accidental logger.Info(""), and report each one to the error handler as ErrEmptyMessage.
+// It applies to every record, so Log with a value that is not a LoggableRecord, which carries
+// data but no message, is dropped as well. It is off by default.
+func (l *Logger) SetRequireMessage(required bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.requireMessage = required
+}
+
+// messageAllowed reports whether a record with msg may be written, reporting the drop when
+// it may not.
+func (l *Logger) messageAllowed(msg string) bool {
+	if msg != "" {
+		return true
+	}
+
+	l.mutex.Lock()
+	opts := l.opts
+	l.mutex.Unlock()
+
+	if !opts.requireMessage {
+		return true
+	}
+
+	opts.handleError(ErrEmptyMessage)
+
+	return false
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+// failingWriter fails every write with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestErrorHandlerReceivesWriteErrors(t *testing.T) {
+	errDisk := errors.New("disk full")
+
+	var got []error
+
+	l := NewWithWriter("app", DebugLevel, failingWriter{err: errDisk})
+	l.SetErrorHandler(func(err error) { got = append(got, err) })
+
+	l.Info("first")
+	l.Error("second")
+
+	if len(got) != 2 {
+		t.Fatalf("handler called %d times, want 2", len(got))
+	}
+
+	for _, err := range got {
+		if !errors.Is(err, errDisk) {
+			t.Errorf("handler got %v, want %v", err, errDisk)
+		}
+	}
+}
+
+func TestErrorHandlerReceivesEncodeErrors(t *testing.T) {
+	var got error
+
+	l := NewWithWriter("app", DebugLevel, failingWriter{})
+	l.SetErrorHandler(func(err error) { got = err })
+
+	l.With(Data{"ratio": math.NaN()}).Info("unencodable")
+
+	if got == nil {
+		t.Error("handler was not told about a record that could not be encoded")
+	}
+}
+
+func TestErrorHandlerIsInheritedAndResettable(t *testing.T) {
+	var calls int
+
+	l := NewWithWriter("app", DebugLevel, failingWriter{err: errors.New("closed")})
+	l.SetErrorHandler(func(error) { calls++ })
+
+	l.With(Data{"user": 1}).Info("child")
+
+	if calls != 1 {
+		t.Errorf("handler called %d times for a derived logger, want 1", calls)
+	}
+
+	l.SetErrorHandler(nil)
+
+	if l.opts.errorHandler != nil {
+		t.Error("SetErrorHandler(nil) did not restore the default")
+	}
+}
+
+func TestSetRequireMessage(t *testing.T) {
+	var buf bytes.Buffer
+	var got []error
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetErrorHandler(func(err error) { got = append(got, err) })
+	l.SetRequireMessage(true)
+
+	l.Info("")
+	l.Log(InfoLevel, Data{"user": 1})
+	l.Info("kept")
+
+	if msgs := messages(decodeRecords(t, &buf)); len(msgs) != 1 || msgs[0] != "kept" {
+		t.Errorf("wrote %q, want only the record with a message", msgs)
+	}
+
+	if len(got) != 2 || !errors.Is(got[0], ErrEmptyMessage) || !errors.Is(got[1], ErrEmptyMessage) {
+		t.Errorf("handler got %v, want ErrEmptyMessage twice", got)
+	}
+
+	l.SetRequireMessage(false)
+	l.Info("")
+
+	if records := decodeRecords(t, &buf); len(records) != 1 || len(got) != 2 {
+		t.Errorf("with the requirement off, wrote %d records and reported %d errors", len(records), len(got))
+	}
+}
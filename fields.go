@@ -0,0 +1,210 @@
+package log
+
+import (
+	"strings"
+	"time"
+)
+
+// Money records a monetary amount as integer minor units plus an ISO 4217 currency code so
+// amounts are never logged as lossy floats.
+//
+// log.Money("total", 1234, "USD") => {"total": {"amount": 1234, "currency": "USD"}}
+func Money(key string, minorUnits int64, currency string) Loggable {
+	return Data{
+		key: Data{
+			"amount":   minorUnits,
+			"currency": currency,
+		},
+	}
+}
+
+// redactedValue replaces the value of any field considered sensitive.
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeyParts are matched case-insensitively against keys that are redacted by default.
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "private_key", "credential"}
+
+// isSensitiveKey reports whether key looks like it holds a secret.
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConfigChange records the keys that changed during a configuration reload. Each entry maps a
+// key to its [from, to] values. Values of keys that look sensitive (passwords, tokens,
+// secrets) are redacted.
+//
+// log.ConfigChange(map[string][2]any{"workers": {4, 8}})
+// => {"config_change": {"workers": {"from": 4, "to": 8}}}
+func ConfigChange(changes map[string][2]any) Loggable {
+	set := make(Data, len(changes))
+
+	for key, change := range changes {
+		from, to := change[0], change[1]
+
+		if isSensitiveKey(key) {
+			from, to = redactedValue, redactedValue
+		}
+
+		set[key] = Data{"from": from, "to": to}
+	}
+
+	return Data{"config_change": set}
+}
+
+// Duration records d in milliseconds, with sub-millisecond precision, under key suffixed with
+// "_ms" so every timing field has the same unit and the unit is visible in the name.
+//
+// log.Duration("latency", 1500*time.Microsecond) => {"latency_ms": 1.5}
+func Duration(key string, d time.Duration) Data {
+	return Data{key + "_ms": float64(d) / float64(time.Millisecond)}
+}
+
+// WithDuration is shorthand for With(Duration(key, time.Since(start))).
+func (l *Logger) WithDuration(key string, start time.Time) *Logger {
+	return l.With(Duration(key, time.Since(start)))
+}
+
+// Since is Duration(key, time.Since(start)), for timing an operation without a separate
+// variable for its length.
+//
+// log.Since("query", start) => {"query_ms": 12.3}
+func Since(key string, start time.Time) Data {
+	return Duration(key, time.Since(start))
+}
+
+// Bytes records a size in bytes under key suffixed with "_bytes", following the convention of
+// Duration.
+//
+// log.Bytes("body", 512) => {"body_bytes": 512}
+func Bytes(key string, n int64) Data {
+	return Data{key + "_bytes": n}
+}
+
+// Count records a number of things under key suffixed with "_count", so counts are never
+// confused with identifiers or sizes.
+//
+// log.Count("retries", 3) => {"retries_count": 3}
+func Count(key string, n int) Data {
+	return Data{key + "_count": n}
+}
+
+// Fields builds data from typed values. Nothing is boxed into an interface while fields are
+// added, and when a *Fields is passed to Log the values are written straight into the record
+// rather than collected into a map of their own first, so a record costs less than the same
+// fields given as Data. Reset lets one Fields be reused across records, in which case a
+// record filtered out by level allocates nothing:
+//
+//	var f log.Fields
+//
+//	for _, job := range jobs {
+//		logger.Log(log.DebugLevel, f.Reset().Str("job", job.ID).Int("attempt", job.Attempt))
+//	}
+//
+// A *Fields is a Loggable and composes with With like Data does, though With converts it into
+// a map like any other Loggable. A key added twice keeps the last value. The zero value is
+// ready to use. A Fields must not be copied once in use.
+type Fields struct {
+	list []typedField
+}
+
+// typedField holds one value of Fields in the member matching its kind.
+type typedField struct {
+	key  string
+	kind fieldKind
+	str  string
+	num  int64
+}
+
+type fieldKind uint8
+
+const (
+	strKind fieldKind = iota
+	intKind
+	boolKind
+)
+
+// Reset removes every field, keeping the allocated space, and returns f for chaining. The
+// records and loggers f was given to keep their own copy of its values.
+func (f *Fields) Reset() *Fields {
+	f.list = f.list[:0]
+	return f
+}
+
+// Str adds a string field and returns f for chaining.
+func (f *Fields) Str(key, value string) *Fields {
+	f.list = append(f.list, typedField{key: key, kind: strKind, str: value})
+	return f
+}
+
+// Int adds an integer field and returns f for chaining.
+func (f *Fields) Int(key string, value int64) *Fields {
+	f.list = append(f.list, typedField{key: key, kind: intKind, num: value})
+	return f
+}
+
+// Bool adds a boolean field and returns f for chaining.
+func (f *Fields) Bool(key string, value bool) *Fields {
+	var num int64
+
+	if value {
+		num = 1
+	}
+
+	f.list = append(f.list, typedField{key: key, kind: boolKind, num: num})
+
+	return f
+}
+
+// Log implements Loggable, converting the fields into a Data map.
+func (f *Fields) Log() map[string]any {
+	set := make(Data, len(f.list))
+
+	for _, field := range f.list {
+		set[field.key] = field.value()
+	}
+
+	return set
+}
+
+// merge adds the fields to the data of a record being built, as mergeField does for Data.
+func (f *Fields) merge(set map[string]any, strategy MergeStrategy) {
+	for i, field := range f.list {
+		if f.replacedAfter(i) {
+			continue
+		}
+
+		mergeField(set, field.key, field.value(), strategy, 0)
+	}
+}
+
+// replacedAfter reports whether the key of field i is added again later, in which case the
+// later value is the one kept.
+func (f *Fields) replacedAfter(i int) bool {
+	for _, later := range f.list[i+1:] {
+		if later.key == f.list[i].key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// value boxes the field's value.
+func (field typedField) value() any {
+	switch field.kind {
+	case intKind:
+		return field.num
+	case boolKind:
+		return field.num != 0
+	}
+
+	return field.str
+}
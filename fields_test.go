@@ -0,0 +1,196 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFieldsLog(t *testing.T) {
+	var f Fields
+
+	got := f.Str("user", "ada").Int("attempt", 3).Bool("cached", true).Str("user", "bob").Log()
+	want := map[string]any{"user": "bob", "attempt": int64(3), "cached": true}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Log() = %v, want %v", got, want)
+	}
+
+	if got := f.Reset().Bool("retry", false).Log(); !reflect.DeepEqual(got, map[string]any{"retry": false}) {
+		t.Errorf("after Reset, Log() = %v", got)
+	}
+}
+
+func TestFieldsReuseKeepsRecordsApart(t *testing.T) {
+	var buf bytes.Buffer
+	var f Fields
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	child := l.With(f.Reset().Str("request", "r-1"))
+	f.Reset().Str("request", "r-2")
+
+	if got := recordData(t, child)["request"]; got != "r-1" {
+		t.Errorf("child request = %v, want r-1", got)
+	}
+}
+
+func TestFieldsWriteLikeData(t *testing.T) {
+	for _, groups := range [][]string{nil, {"req"}} {
+		var viaFields, viaData bytes.Buffer
+
+		l := goldenLogger(&viaFields).With(Data{"user": "ada"})
+
+		for _, group := range groups {
+			l = l.WithGroup(group)
+		}
+
+		var f Fields
+		l.Log(InfoLevel, f.Str("user", "bob").Int("attempt", 1).Int("attempt", 2).Bool("cached", true))
+
+		l.SetOutput(&viaData)
+		l.Log(InfoLevel, Data{"user": "bob", "attempt": int64(2), "cached": true})
+
+		if !bytes.Equal(viaFields.Bytes(), viaData.Bytes()) {
+			t.Errorf("groups %v: Fields wrote\n%s\nData wrote\n%s", groups, viaFields.Bytes(), viaData.Bytes())
+		}
+	}
+}
+
+func TestFilteredFieldsDoNotAllocate(t *testing.T) {
+	l := NewWithWriter("app", InfoLevel, io.Discard)
+	id := "u-1234"
+
+	var f Fields
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Log(DebugLevel, f.Reset().Str("user", id).Int("attempt", 3).Bool("cached", true))
+	})
+
+	if allocs != 0 {
+		t.Errorf("a filtered record with reused Fields allocated %v times", allocs)
+	}
+}
+
+// BenchmarkFields logs three typed values through a reused Fields and through Data, for a
+// record below the level and for one that is written.
+func BenchmarkFields(b *testing.B) {
+	id := "u-1234"
+
+	for _, level := range []Level{DebugLevel, ErrorLevel} {
+		name := "filtered"
+
+		if level == ErrorLevel {
+			name = "written"
+		}
+
+		l := NewWithWriter("app", InfoLevel, io.Discard)
+		l.SetIncludeSource(false)
+
+		b.Run(name+"/fields", func(b *testing.B) {
+			var f Fields
+
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				l.Log(level, f.Reset().Str("user", id).Int("attempt", int64(i)).Bool("cached", true))
+			}
+		})
+
+		b.Run(name+"/data", func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				l.Log(level, Data{"user": id, "attempt": int64(i), "cached": true})
+			}
+		})
+	}
+}
+
+// helperData returns the data of a record carrying value.
+func helperData(t *testing.T, value Loggable) map[string]any {
+	t.Helper()
+
+	return recordData(t, NewWithWriter("app", DebugLevel, io.Discard).With(value))
+}
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     map[string]any
+	}{
+		{1234, "USD", map[string]any{"total": map[string]any{"amount": 1234.0, "currency": "USD"}}},
+		{-500, "EUR", map[string]any{"total": map[string]any{"amount": -500.0, "currency": "EUR"}}},
+		{0, "JPY", map[string]any{"total": map[string]any{"amount": 0.0, "currency": "JPY"}}},
+	}
+
+	for _, tt := range tests {
+		if got := helperData(t, Money("total", tt.amount, tt.currency)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Money(%d, %s) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestConfigChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string][2]any
+		want    map[string]any
+	}{
+		{"values", map[string][2]any{"workers": {4, 8}, "mode": {"fast", "safe"}}, map[string]any{
+			"workers": map[string]any{"from": 4.0, "to": 8.0},
+			"mode":    map[string]any{"from": "fast", "to": "safe"},
+		}},
+		{"sensitive", map[string][2]any{"db_password": {"old", "new"}}, map[string]any{
+			"db_password": map[string]any{"from": redactedValue, "to": redactedValue},
+		}},
+		{"added", map[string][2]any{"region": {nil, "eu"}}, map[string]any{
+			"region": map[string]any{"from": nil, "to": "eu"},
+		}},
+		{"empty", map[string][2]any{}, map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		got := helperData(t, ConfigChange(tt.changes))
+		want := map[string]any{"config_change": tt.want}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: ConfigChange() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestUnitHelpers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Loggable
+		want  map[string]any
+	}{
+		{"duration", Duration("latency", 1500*time.Microsecond), map[string]any{"latency_ms": 1.5}},
+		{"zero duration", Duration("latency", 0), map[string]any{"latency_ms": 0.0}},
+		{"negative duration", Duration("skew", -2*time.Millisecond), map[string]any{"skew_ms": -2.0}},
+		{"bytes", Bytes("body", 512), map[string]any{"body_bytes": 512.0}},
+		{"zero bytes", Bytes("body", 0), map[string]any{"body_bytes": 0.0}},
+		{"count", Count("retries", 3), map[string]any{"retries_count": 3.0}},
+		{"zero count", Count("retries", 0), map[string]any{"retries_count": 0.0}},
+	}
+
+	for _, tt := range tests {
+		if got := helperData(t, tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSince(t *testing.T) {
+	start := time.Now().Add(-2 * time.Second)
+	got := helperData(t, Since("query", start))
+
+	ms, ok := got["query_ms"].(float64)
+
+	if !ok || ms < 2000 || ms > 60000 {
+		t.Errorf("Since() = %v, want query_ms of about 2000", got)
+	}
+}
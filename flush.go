@@ -0,0 +1,156 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Flusher is implemented by writers that buffer records or deliver them in the background.
+// Flush returns once everything written so far has been delivered.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush flushes the logger's writer, level writers and sinks, where they implement Flusher,
+// and waits for OnEmit observers to catch up. It returns the first error encountered.
+func (l *Logger) Flush() error {
+	var first error
+
+	for _, w := range l.writers() {
+		if f, ok := w.(Flusher); ok {
+			if err := f.Flush(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	l.mutex.Lock()
+	emitters := l.opts.emitters
+	l.mutex.Unlock()
+
+	for _, e := range emitters {
+		e.wait()
+	}
+
+	return first
+}
+
+// DrainError is returned by Drain when its context ends before every record was delivered.
+type DrainError struct {
+	// Dropped is the number of records still undelivered, as reported by writers with a
+	// Pending method.
+	Dropped int
+	// Err is the context's error.
+	Err error
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("log: drain: %d records undelivered: %s", e.Dropped, e.Err)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+// Drain is Flush bounded by ctx, for runtimes such as lambdas that freeze the process once a
+// handler returns and would lose records still on their way:
+//
+//	ctx, cancel := context.WithTimeout(ctx, time.Second)
+//	defer cancel()
+//	err := logger.Drain(ctx)
+//
+// It returns the result of Flush when that finishes first. When ctx ends first it returns a
+// *DrainError counting the records that writers such as PostWriter report as pending; the
+// flush carries on in the background and may still deliver them.
+func (l *Logger) Drain(ctx context.Context) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- l.Flush()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	dropped := 0
+
+	for _, w := range l.writers() {
+		if p, ok := w.(interface{ Pending() int }); ok {
+			dropped += p.Pending()
+		}
+	}
+
+	return &DrainError{Dropped: dropped, Err: ctx.Err()}
+}
+
+// Close flushes the logger's writer, level writers and sinks and then closes those that
+// implement io.Closer, so buffered records are not lost when the program exits:
+//
+//	logger := log.SetupLogger("api", build)
+//	defer logger.Close()
+//
+// The process's standard streams are never closed, so a logger writing straight to os.Stdout
+// or os.Stderr can be closed without silencing the rest of the program. Writers are shared
+// with the loggers derived from l, so close only the logger the writers were set on, and only
+// once the others are done with them. It returns the first error encountered.
+func (l *Logger) Close() error {
+	first := l.Flush()
+
+	for _, w := range l.writers() {
+		if f, ok := w.(*os.File); ok && (f == os.Stdout || f == os.Stderr) {
+			continue
+		}
+
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+// writers returns the primary writer followed by the level writers and sinks, each listed
+// once.
+func (l *Logger) writers() []io.Writer {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	list := make([]io.Writer, 0, len(l.opts.levelWriters)+1)
+
+	add := func(w io.Writer) {
+		if w == nil {
+			return
+		}
+
+		// writers of a type that cannot be compared are never shared by value
+		if reflect.TypeOf(w).Comparable() {
+			for _, added := range list {
+				if added == w {
+					return
+				}
+			}
+		}
+
+		list = append(list, w)
+	}
+
+	add(l.Out)
+
+	for _, route := range l.opts.levelWriters {
+		add(route.w)
+	}
+
+	for _, sink := range l.opts.sinks {
+		add(sink.w)
+	}
+
+	return list
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// closeWriter counts the calls made to it.
+type closeWriter struct {
+	bytes.Buffer
+	flushes, closes int
+}
+
+func (w *closeWriter) Flush() error { w.flushes++; return nil }
+func (w *closeWriter) Close() error { w.closes++; return nil }
+
+func TestCloseFlushesAndClosesWriters(t *testing.T) {
+	out, route, sink := &closeWriter{}, &closeWriter{}, &closeWriter{}
+
+	l := NewWithWriter("app", DebugLevel, out)
+	l.SetLevelWriter(ErrorLevel, route)
+	l.SetLevelWriter(AlertLevel, out)
+	l.AddSink(sink, nil)
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, w := range map[string]*closeWriter{"out": out, "route": route, "sink": sink} {
+		if w.flushes != 1 || w.closes != 1 {
+			t.Errorf("%s flushed %d and closed %d times, want once each", name, w.flushes, w.closes)
+		}
+	}
+}
+
+func TestCloseLeavesStandardStreamsOpen(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, os.Stderr)
+	l.SetLevelWriter(ErrorLevel, os.Stdout)
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if _, err := f.Stat(); err != nil {
+			t.Errorf("%s was closed: %v", f.Name(), err)
+		}
+	}
+}
+
+// stuckWriter never finishes a flush until released.
+type stuckWriter struct {
+	bytes.Buffer
+	release chan struct{}
+}
+
+func (w *stuckWriter) Flush() error { <-w.release; return nil }
+func (w *stuckWriter) Pending() int { return 3 }
+
+func TestDrainReportsPendingRecords(t *testing.T) {
+	w := &stuckWriter{release: make(chan struct{})}
+	defer close(w.release)
+
+	l := NewWithWriter("app", DebugLevel, w)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var drainErr *DrainError
+
+	if err := l.Drain(ctx); !errors.As(err, &drainErr) || drainErr.Dropped != 3 {
+		t.Fatalf("Drain = %v, want a DrainError with 3 dropped", err)
+	}
+
+	if !errors.Is(drainErr, context.DeadlineExceeded) {
+		t.Errorf("DrainError wraps %v, want the context error", drainErr.Err)
+	}
+}
@@ -0,0 +1,521 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formatter encodes a record into the bytes handed to a logger's writer. Formatters keep
+// writers format agnostic: a writer only ever sees the encoded bytes.
+type Formatter interface {
+	Format(rec WriteLog) ([]byte, error)
+}
+
+// Format selects one of the built-in formatters.
+type Format int
+
+const (
+	// JSONFormat writes each record as a JSON object. This is the default.
+	JSONFormat Format = iota
+	// LogfmtFormat writes each record as space separated key=value pairs.
+	LogfmtFormat
+)
+
+// SetFormat changes the encoding used for records written by this logger to one of the
+// built-in formats. It replaces any formatter set with SetFormatter.
+func (l *Logger) SetFormat(format Format) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.format = format
+	l.opts.formatter = nil
+}
+
+// SetFormatter installs a custom formatter for records written by this logger. Passing nil
+// restores the built-in format selected by SetFormat.
+func (l *Logger) SetFormatter(f Formatter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.formatter = f
+}
+
+type sink struct {
+	w         io.Writer
+	formatter Formatter
+}
+
+// AddSink additionally writes every record to w, encoded by f, so one call can produce
+// compact JSON for a collector and readable lines for a terminal at the same time:
+//
+//	logger.SetOutput(log.NewPostWriter(url))
+//	logger.AddSink(os.Stderr, log.LogfmtFormatter{})
+//
+// A nil f uses the logger's built-in format. Writers implementing RecordWriter, such as
+// ConsoleWriter, receive the record itself and ignore f. A sink that fails to encode or write
+// a record reports the error to the error handler without affecting the other destinations.
+// Each call adds a sink; loggers derived from l afterwards write to the same sinks.
+func (l *Logger) AddSink(w io.Writer, f Formatter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	sinks := l.opts.sinks
+	l.opts.sinks = append(sinks[:len(sinks):len(sinks)], sink{w: w, formatter: f})
+}
+
+// currentFormatter resolves the formatter selected by o.
+func (o *options) currentFormatter() Formatter {
+	if o.formatter != nil {
+		return o.formatter
+	}
+
+	switch o.format {
+	case LogfmtFormat:
+		return LogfmtFormatter{}
+	default:
+		return JSONFormatter{FieldOrder: o.fieldOrder, FieldNames: o.fieldNames}
+	}
+}
+
+// SetFieldOrder fixes the order of fields in the logger's built-in JSON output. Names may
+// refer to core fields (time, level, level_num, app, host, pid, msg, src, data) or to keys
+// inside data. Core fields that are not listed keep their default relative order after the
+// listed ones; unlisted data keys are appended after the listed ones in sorted order so the
+// output is byte-for-byte stable. Passing no names restores the default encoding.
+func (l *Logger) SetFieldOrder(order []string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.fieldOrder = append([]string(nil), order...)
+}
+
+// SetFieldNames renames core fields in the logger's built-in JSON output, for consumers that
+// expect an existing schema. Keys are the default names (time, level, level_num, app, host,
+// pid, msg, src, data) and values the names to write instead:
+//
+//	logger.SetFieldNames(map[string]string{"msg": "message", "time": "@timestamp"})
+//
+// Passing nil restores the default names.
+func (l *Logger) SetFieldNames(names map[string]string) {
+	var set map[string]string
+
+	if len(names) > 0 {
+		set = make(map[string]string, len(names))
+
+		for key, value := range names {
+			set[key] = value
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.fieldNames = set
+}
+
+// SetIndent spreads each JSON record over several lines, every line starting with prefix and
+// indented one more time per level of nesting, which is easier to read while debugging.
+// Indented records are no longer one per line, so the output stops being newline delimited
+// JSON; keep it off for anything a machine reads. It applies to the output of any formatter
+// that produces JSON and leaves other formats, such as logfmt, unchanged. Passing two empty
+// strings restores compact output.
+func (l *Logger) SetIndent(prefix, indent string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.indentPrefix = prefix
+	l.opts.indent = indent
+}
+
+// indentJSON writes data indented as configured in o to buf. It reports false, leaving data to
+// be written as it is, when data is not JSON.
+func indentJSON(buf *bytes.Buffer, data []byte, o *options) bool {
+	if err := json.Indent(buf, data, o.indentPrefix, o.indent); err != nil {
+		buf.Reset()
+		return false
+	}
+
+	return true
+}
+
+// JSONFormatter encodes records as a single JSON object. It is the default formatter.
+type JSONFormatter struct {
+	// FieldOrder optionally fixes the order of fields. See SetFieldOrder.
+	FieldOrder []string
+	// FieldNames optionally renames core fields. See SetFieldNames.
+	FieldNames map[string]string
+}
+
+// custom reports whether the formatter's settings need the ordered encoder rather than the
+// record's own MarshalJSON.
+func (f JSONFormatter) custom() bool {
+	return len(f.FieldOrder) > 0 || len(f.FieldNames) > 0
+}
+
+// Format implements Formatter.
+func (f JSONFormatter) Format(rec WriteLog) ([]byte, error) {
+	if f.custom() {
+		return marshalOrdered(rec, f.FieldOrder, f.FieldNames)
+	}
+
+	return rec.MarshalJSON()
+}
+
+// formatTo encodes rec into buf without allocating an intermediate slice. The bytes are
+// identical to Format's.
+func (f JSONFormatter) formatTo(buf *bytes.Buffer, rec WriteLog) error {
+	if f.custom() {
+		data, err := marshalOrdered(rec, f.FieldOrder, f.FieldNames)
+		buf.Write(data)
+		return err
+	}
+
+	return rec.encodeJSON(buf)
+}
+
+// bufferFormatter is implemented by formatters that can encode straight into a pooled buffer.
+type bufferFormatter interface {
+	formatTo(buf *bytes.Buffer, rec WriteLog) error
+}
+
+// maxPooledBuffer keeps unusually large records from pinning memory in the pool.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// formatInto encodes rec with f, appending to buf.
+func formatInto(buf *bytes.Buffer, f Formatter, rec WriteLog) error {
+	if bf, ok := f.(bufferFormatter); ok {
+		return bf.formatTo(buf, rec)
+	}
+
+	data, err := f.Format(rec)
+	buf.Write(data)
+
+	return err
+}
+
+// LogfmtFormatter encodes records as logfmt.
+type LogfmtFormatter struct{}
+
+// Format implements Formatter.
+func (LogfmtFormatter) Format(rec WriteLog) ([]byte, error) {
+	return marshalLogfmt(rec)
+}
+
+// defaultFieldOrder is the order WriteLog.MarshalJSON writes core fields in.
+var defaultFieldOrder = []string{"time", "level", "level_num", "app", "host", "pid", "msg", "src", "data"}
+
+// marshalOrdered encodes out as JSON with fields in the given order and core fields renamed
+// according to names. See SetFieldOrder and SetFieldNames.
+func marshalOrdered(out WriteLog, order []string, names map[string]string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	core := make([]string, 0, len(defaultFieldOrder))
+	seen := make(map[string]bool, len(order))
+
+	for _, name := range order {
+		seen[name] = true
+	}
+
+	for _, name := range order {
+		if isCoreField(name) {
+			core = append(core, name)
+		}
+	}
+
+	for _, name := range defaultFieldOrder {
+		if !seen[name] {
+			core = append(core, name)
+		}
+	}
+
+	buf.WriteByte('{')
+
+	first := true
+
+	for _, name := range core {
+		var value any
+
+		switch name {
+		case "time":
+			value = out.Time
+		case "app":
+			value = out.App
+		case "host":
+			if out.Host == "" {
+				continue
+			}
+			value = out.Host
+		case "pid":
+			if out.PID == 0 {
+				continue
+			}
+			value = out.PID
+		case "level":
+			value = out.Level
+		case "level_num":
+			if out.LevelNum == nil {
+				continue
+			}
+			value = *out.LevelNum
+		case "msg":
+			value = out.Msg
+		case "src":
+			if out.Src.IsZero() {
+				continue
+			}
+			value = out.Src
+		case "data":
+			if len(out.Data) == 0 {
+				continue
+			}
+		}
+
+		if !first {
+			buf.WriteByte(',')
+		}
+
+		first = false
+
+		key := name
+
+		if renamed, ok := names[name]; ok {
+			key = renamed
+		}
+
+		if err := writeJSONKey(&buf, key); err != nil {
+			return nil, err
+		}
+
+		if name == "data" {
+			if err := writeOrderedData(&buf, out.Data, order); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := writeJSONValue(&buf, value); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func writeOrderedData(buf *bytes.Buffer, data Data, order []string) error {
+	keys := make([]string, 0, len(data))
+	listed := make(map[string]bool, len(order))
+
+	for _, name := range order {
+		if _, ok := data[name]; ok && !listed[name] {
+			listed[name] = true
+			keys = append(keys, name)
+		}
+	}
+
+	extra := make([]string, 0, len(data)-len(keys))
+
+	for key := range data {
+		if !listed[key] {
+			extra = append(extra, key)
+		}
+	}
+
+	sort.Strings(extra)
+
+	keys = append(keys, extra...)
+
+	buf.WriteByte('{')
+
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := writeJSONKey(buf, key); err != nil {
+			return err
+		}
+
+		if err := writeJSONValue(buf, data[key]); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+func writeJSONKey(buf *bytes.Buffer, key string) error {
+	if err := writeJSONValue(buf, key); err != nil {
+		return err
+	}
+
+	buf.WriteByte(':')
+
+	return nil
+}
+
+func isCoreField(name string) bool {
+	for _, core := range defaultFieldOrder {
+		if core == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// marshalLogfmt encodes out as logfmt. Core fields come first in the canonical order, then
+// the source location, then data fields in key order. Nested maps are flattened with dotted
+// keys; any other composite value is rendered as quoted JSON.
+//
+// time=2006-01-02T15:04:05Z level=info app=api msg="user login" src=model/user.go:12 user.id=1
+func marshalLogfmt(out WriteLog) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writeLogfmtPair(&buf, "time", out.Time.Format(time.RFC3339Nano))
+	writeLogfmtPair(&buf, "level", out.Level)
+
+	if out.LevelNum != nil {
+		writeLogfmtPair(&buf, "level_num", strconv.Itoa(*out.LevelNum))
+	}
+
+	writeLogfmtPair(&buf, "app", out.App)
+
+	if out.Host != "" {
+		writeLogfmtPair(&buf, "host", out.Host)
+	}
+
+	if out.PID != 0 {
+		writeLogfmtPair(&buf, "pid", strconv.Itoa(out.PID))
+	}
+
+	writeLogfmtPair(&buf, "msg", out.Msg)
+
+	if !out.Src.IsZero() {
+		writeLogfmtPair(&buf, "src", out.Src.Path()+":"+strconv.Itoa(out.Src.Line))
+	}
+
+	if err := writeLogfmtData(&buf, "", out.Data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeLogfmtData(buf *bytes.Buffer, prefix string, data map[string]any) error {
+	keys := make([]string, 0, len(data))
+
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := data[key]
+
+		switch v := value.(type) {
+		case Data:
+			if err := writeLogfmtData(buf, prefix+key+".", v); err != nil {
+				return err
+			}
+			continue
+		case map[string]any:
+			if err := writeLogfmtData(buf, prefix+key+".", v); err != nil {
+				return err
+			}
+			continue
+		}
+
+		text, err := logfmtValue(value)
+
+		if err != nil {
+			return err
+		}
+
+		writeLogfmtPair(buf, prefix+key, text)
+	}
+
+	return nil
+}
+
+func logfmtValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return v, nil
+	case error:
+		return v.Error(), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	}
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// writeLogfmtPair appends a single key=value pair, quoting the value when it is empty or
+// contains characters that would make the pair ambiguous.
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+
+	if value == "" || strings.IndexFunc(value, needsLogfmtQuote) >= 0 {
+		buf.WriteString(strconv.Quote(value))
+		return
+	}
+
+	buf.WriteString(value)
+}
+
+func needsLogfmtQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f
+}
+
+// logfmtKey replaces characters that are not allowed in a logfmt key.
+func logfmtKey(key string) string {
+	if strings.IndexFunc(key, needsLogfmtQuote) < 0 {
+		return key
+	}
+
+	return strings.Map(func(r rune) rune {
+		if needsLogfmtQuote(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
@@ -0,0 +1,230 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenTime is the clock of every logger built by goldenLogger.
+var goldenTime = time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+
+// entry is a LoggableRecord for writing records whose source and host are fixed, so golden
+// output does not depend on the machine or on line numbers in this file.
+type entry WriteLog
+
+func (e entry) Log() map[string]any { return e.Data }
+func (e entry) Record() WriteLog    { return WriteLog(e) }
+
+// goldenLogger returns a logger writing to buf with a fixed clock and no captured source.
+func goldenLogger(buf *bytes.Buffer) *Logger {
+	l := NewWithWriter("api", DebugLevel, buf)
+	l.SetClock(func() time.Time { return goldenTime })
+	l.SetIncludeSource(false)
+
+	return l
+}
+
+// logGoldenRecords writes the records shared by the golden tests of the formatters.
+func logGoldenRecords(l *Logger) {
+	l.Info("server started")
+
+	l.With(Data{
+		"user":  42,
+		"name":  "ada",
+		"ratio": 0.25,
+		"tags":  []any{"a", "b"},
+		"auth":  Data{"ok": true, "method": "token"},
+		"nil":   nil,
+	}).Warn("slow request")
+
+	l.Log(ErrorLevel, entry{
+		Msg:  `quote " and <html> & tab	in "msg"`,
+		Host: "web-1",
+		PID:  4242,
+		Src:  Src{File: "model/user.go", Line: 12},
+		Data: Data{"err": "connection refused", "attempt": 3},
+	})
+
+	l.With(Data{"path": "/ünïcödé"}).Debug("disk 90% full")
+}
+
+// checkGolden compares got with testdata/name, rewriting the file when -update is given.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestDefaultOutputGolden(t *testing.T) {
+	var buf bytes.Buffer
+
+	logGoldenRecords(goldenLogger(&buf))
+	checkGolden(t, "default.golden", buf.Bytes())
+}
+
+func TestFieldOrderGolden(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetFieldOrder([]string{"msg", "level", "user", "err", "time"})
+
+	logGoldenRecords(l)
+	checkGolden(t, "field_order.golden", buf.Bytes())
+}
+
+func TestFieldNamesGolden(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetFieldNames(map[string]string{"msg": "message", "time": "@timestamp", "data": "fields"})
+
+	logGoldenRecords(l)
+	checkGolden(t, "field_names.golden", buf.Bytes())
+}
+
+func TestIndentGolden(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetIndent("", "  ")
+
+	logGoldenRecords(l)
+	checkGolden(t, "indent.golden", buf.Bytes())
+}
+
+// TestDefaultFormatterMatchesWriter checks that the bytes a writer receives are those of the
+// default JSONFormatter, one record per line.
+func TestDefaultFormatterMatchesWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var recs []WriteLog
+
+	l := goldenLogger(&buf)
+	l.OnEmit(func(rec WriteLog) { recs = append(recs, rec) })
+
+	logGoldenRecords(l)
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var want bytes.Buffer
+
+	for _, rec := range recs {
+		data, err := JSONFormatter{}.Format(rec)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want.Write(data)
+		want.WriteByte('\n')
+	}
+
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Errorf("writer got:\n%s\nJSONFormatter gives:\n%s", buf.Bytes(), want.Bytes())
+	}
+}
+
+func TestSetFormatterReplacesDefault(t *testing.T) {
+	var def, custom bytes.Buffer
+
+	logGoldenRecords(goldenLogger(&def))
+
+	l := goldenLogger(&custom)
+	l.SetFormatter(JSONFormatter{})
+	logGoldenRecords(l)
+
+	if !bytes.Equal(def.Bytes(), custom.Bytes()) {
+		t.Errorf("an explicit JSONFormatter wrote:\n%s\nthe default wrote:\n%s", custom.Bytes(), def.Bytes())
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	var buf, want bytes.Buffer
+
+	l := goldenLogger(&buf)
+	child := l.With(Data{"user": 42}).WithFormat(LogfmtFormat)
+
+	child.Info("as logfmt")
+	l.Info("as json")
+
+	ref := goldenLogger(&want)
+	ref.SetFormat(LogfmtFormat)
+	ref.With(Data{"user": 42}).Info("as logfmt")
+
+	ref.SetFormat(JSONFormat)
+	ref.Info("as json")
+
+	if bytes.HasPrefix(buf.Bytes(), []byte("{")) {
+		t.Errorf("child wrote JSON: %s", buf.Bytes())
+	}
+
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.Bytes(), want.Bytes())
+	}
+}
+
+// plainRecord is WriteLog without its MarshalJSON method, encoded by reflection.
+type plainRecord WriteLog
+
+// BenchmarkInfo writes a record with a few fields at a passing level. marshal only encodes
+// the same record, into a fresh json.Marshal buffer per call as records were before buffers
+// were pooled, for comparison.
+func BenchmarkInfo(b *testing.B) {
+	data := Data{"user": 42, "path": "/users/42", "cached": true}
+
+	b.Run("pooled", func(b *testing.B) {
+		l := NewWithWriter("app", DebugLevel, io.Discard).With(data)
+		l.SetIncludeSource(false)
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			l.Info("request done")
+		}
+	})
+
+	b.Run("marshal", func(b *testing.B) {
+		rec := WriteLog{Time: goldenTime, Level: "info", App: "app", Msg: "request done", Data: data}
+
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			p, err := json.Marshal(plainRecord(rec.copy()))
+
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			_, _ = io.Discard.Write(append(p, '\n'))
+		}
+	})
+}
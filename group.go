@@ -0,0 +1,41 @@
+package log
+
+// WithGroup returns a copy of the logger that nests the data of later With and Log calls under
+// name, so related fields stay together and cannot collide with those of other subsystems:
+//
+//	logger.WithGroup("http").With(log.Data{"method": "GET", "status": 200})
+//	// => {... "data":{"http":{"method":"GET","status":200}} ...}
+//
+// Groups compose, each call nesting one level deeper. Data added before WithGroup stays where
+// it was. Fields given under the same group by separate calls are merged into one map, and a
+// key repeated inside a group follows the merge strategy just as it would at the top level.
+// An empty name returns a plain copy.
+func (l *Logger) WithGroup(name string) *Logger {
+	child := l.clone()
+
+	if name != "" {
+		child.groups = append(l.groups[:len(l.groups):len(l.groups)], name)
+	}
+
+	return child
+}
+
+// grouped nests a copy of fields under the logger's groups, innermost last. Without groups
+// fields is returned as is.
+func (l *Logger) grouped(fields map[string]any) map[string]any {
+	if len(l.groups) == 0 || len(fields) == 0 {
+		return fields
+	}
+
+	nested := make(Data, len(fields))
+
+	for key, value := range fields {
+		nested[key] = value
+	}
+
+	for i := len(l.groups) - 1; i >= 0; i-- {
+		nested = Data{l.groups[i]: nested}
+	}
+
+	return nested
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWithGroup(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"service": "api"})
+	http := l.WithGroup("http")
+
+	tests := []struct {
+		name string
+		l    *Logger
+		want map[string]any
+	}{
+		{"grouped", http.With(Data{"method": "GET"}), map[string]any{
+			"service": "api",
+			"http":    map[string]any{"method": "GET"},
+		}},
+		{"merged", http.With(Data{"method": "GET"}).With(Data{"status": 200}), map[string]any{
+			"service": "api",
+			"http":    map[string]any{"method": "GET", "status": 200.0},
+		}},
+		{"nested", http.WithGroup("req").With(Data{"id": "r-1"}).WithGroup("").With(Data{"size": 1}), map[string]any{
+			"service": "api",
+			"http":    map[string]any{"req": map[string]any{"id": "r-1", "size": 1.0}},
+		}},
+		{"siblings", http.With(Data{"method": "GET"}).WithGroup("db").With(Data{"rows": 3}), map[string]any{
+			"service": "api",
+			"http":    map[string]any{"method": "GET", "db": map[string]any{"rows": 3.0}},
+		}},
+		{"no data", http, map[string]any{"service": "api"}},
+	}
+
+	for _, tt := range tests {
+		if got := recordData(t, tt.l); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: data = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
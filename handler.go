@@ -0,0 +1,58 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+type levelPayload struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// LevelHandler returns an admin endpoint for changing the level at runtime. GET responds with
+// the current level as {"level":"info"}. PUT and POST set the level from a "level" query
+// parameter or a {"level":"debug"} body and respond with the new level. Unknown levels are
+// rejected with 400 Bad Request.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			value := r.URL.Query().Get("level")
+
+			if value == "" {
+				var body levelPayload
+
+				if err := json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&body); err != nil {
+					writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: "invalid body: " + err.Error()})
+					return
+				}
+
+				value = body.Level
+			}
+
+			level, err := ParseLevel(value)
+
+			if err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+				return
+			}
+
+			l.SetLevel(level)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeLevelPayload(w, http.StatusMethodNotAllowed, levelPayload{Error: "method not allowed"})
+			return
+		}
+
+		writeLevelPayload(w, http.StatusOK, levelPayload{Level: l.Level().String()})
+	})
+}
+
+func writeLevelPayload(w http.ResponseWriter, status int, payload levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
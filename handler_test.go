@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	l := NewWithWriter("app", InfoLevel, io.Discard)
+	srv := httptest.NewServer(l.LevelHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		body   string
+		status int
+		want   levelPayload
+		level  Level
+	}{
+		{"get", http.MethodGet, "", "", http.StatusOK, levelPayload{Level: "info"}, InfoLevel},
+		{"put query", http.MethodPut, "?level=DEBUG", "", http.StatusOK, levelPayload{Level: "debug"}, DebugLevel},
+		{"post body", http.MethodPost, "", `{"level":"error"}`, http.StatusOK, levelPayload{Level: "error"}, ErrorLevel},
+		{"unknown level", http.MethodPut, "?level=loud", "", http.StatusBadRequest, levelPayload{Error: `log: unknown level "loud"`}, ErrorLevel},
+		{"malformed body", http.MethodPut, "", `{"level":`, http.StatusBadRequest, levelPayload{}, ErrorLevel},
+		{"get after errors", http.MethodGet, "", "", http.StatusOK, levelPayload{Level: "error"}, ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+tt.query, strings.NewReader(tt.body))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := srv.Client().Do(req)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got levelPayload
+
+		err = json.NewDecoder(res.Body).Decode(&got)
+		res.Body.Close()
+
+		if err != nil {
+			t.Fatalf("%s: decode response: %v", tt.name, err)
+		}
+
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, res.StatusCode, tt.status)
+		}
+
+		if got.Level != tt.want.Level || tt.want.Error != "" && got.Error != tt.want.Error {
+			t.Errorf("%s: response %+v, want %+v", tt.name, got, tt.want)
+		}
+
+		if tt.status != http.StatusOK && got.Error == "" {
+			t.Errorf("%s: error response carries no error", tt.name)
+		}
+
+		if l.Level() != tt.level {
+			t.Errorf("%s: logger level %s, want %s", tt.name, l.Level(), tt.level)
+		}
+	}
+}
+
+func TestLevelHandlerRejectsOtherMethods(t *testing.T) {
+	l := NewWithWriter("app", InfoLevel, io.Discard)
+	res := httptest.NewRecorder()
+
+	l.LevelHandler().ServeHTTP(res, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	if res.Code != http.StatusMethodNotAllowed || res.Header().Get("Allow") != "GET, PUT, POST" {
+		t.Errorf("DELETE got %d with Allow %q", res.Code, res.Header().Get("Allow"))
+	}
+}
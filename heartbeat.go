@@ -0,0 +1,56 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// StartHeartbeat writes a NoticeLevel "heartbeat" record every interval until the returned
+// stop function is called. Each heartbeat carries a few runtime stats so log-only monitoring
+// gets a cheap liveness and health signal. Heartbeats bypass sampling, rate limiting and
+// SetDedup, so only the level can silence them. Calling stop more than once is safe; it
+// returns after the heartbeat goroutine has exited.
+func (l *Logger) StartHeartbeat(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				l.heartbeat()
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+func (l *Logger) heartbeat() {
+	var stats runtime.MemStats
+
+	runtime.ReadMemStats(&stats)
+
+	l.diagnostic().With(Data{
+		"goroutines": runtime.NumGoroutine(),
+		"heap_alloc": stats.HeapAlloc,
+		"num_gc":     stats.NumGC,
+	}).output(2, NoticeLevel, "heartbeat", nil)
+}
@@ -0,0 +1,24 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatIgnoresFiltering(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetSampler(1, 0)
+	l.SetRateLimit(1)
+	l.SetDedup(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		l.heartbeat()
+	}
+
+	if n := bytes.Count(buf.Bytes(), []byte(`"msg":"heartbeat"`)); n != 3 {
+		t.Fatalf("wrote %d heartbeats, want 3:\n%s", n, buf.Bytes())
+	}
+}
@@ -0,0 +1,15 @@
+package log
+
+// AddHook registers fn to run on every record this logger writes, after the record has been
+// assembled and redacted but before it is encoded. Hooks run in registration order and may
+// change the record in place: rewrite Msg, add or drop Data fields and so on. The data is
+// walked again once the hooks are done, so fields they add are resolved, bounded by
+// SetMaxDepth and masked like any other. They run synchronously on the logging goroutine, so
+// keep them fast.
+func (l *Logger) AddHook(fn func(*WriteLog)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// force a copy so loggers created by With before this call keep their own list
+	l.opts.hooks = append(l.opts.hooks[:len(l.opts.hooks):len(l.opts.hooks)], fn)
+}
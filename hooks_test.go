@@ -0,0 +1,74 @@
+package log
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestHooksRunInRegistrationOrder(t *testing.T) {
+	var order []string
+
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+
+	for _, name := range []string{"first", "second", "third"} {
+		name := name
+
+		l.AddHook(func(rec *WriteLog) {
+			order = append(order, name)
+			rec.Msg += " " + name
+		})
+	}
+
+	var msg string
+
+	l.AddHook(func(rec *WriteLog) { msg = rec.Msg })
+	l.Info("saved")
+
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("hooks ran in order %v, want %v", order, want)
+	}
+
+	if msg != "saved first second third" {
+		t.Errorf("last hook saw msg %q", msg)
+	}
+}
+
+func TestHooksAddAndDropFields(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"internal": "x", "user": 1})
+
+	l.AddHook(func(rec *WriteLog) {
+		delete(rec.Data, "internal")
+		rec.Data["region"] = "eu"
+	})
+
+	// a later hook sees what the earlier one left
+	l.AddHook(func(rec *WriteLog) {
+		if _, ok := rec.Data["region"]; ok {
+			rec.Data["tagged"] = true
+		}
+	})
+
+	got := recordData(t, l)
+	want := map[string]any{"user": float64(1), "region": "eu", "tagged": true}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+}
+
+func TestHooksAddedLaterSkipEarlierChildren(t *testing.T) {
+	var calls int
+
+	parent := NewWithWriter("app", DebugLevel, io.Discard)
+	child := parent.With(Data{"user": 1})
+
+	parent.AddHook(func(*WriteLog) { calls++ })
+
+	child.Info("before")
+	parent.With().Info("after")
+
+	if calls != 1 {
+		t.Errorf("hook ran %d times, want once for the logger derived after AddHook", calls)
+	}
+}
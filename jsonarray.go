@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONArrayWriter writes records as the elements of a single JSON array rather than one per
+// line, for tools that expect one well formed document. The array is finished by Close, which
+// also closes the underlying writer when it is an io.Closer other than os.Stdout or
+// os.Stderr; until then the output is incomplete. An array without records is written as []. Use it with the JSON based formatters
+// and keep SetIndent off.
+type JSONArrayWriter struct {
+	mutex   sync.Mutex
+	w       io.Writer
+	started bool
+	closed  bool
+}
+
+// NewJSONArrayWriter returns a writer that streams the array to w.
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{w: w}
+}
+
+// Write adds a single encoded record to the array.
+func (a *JSONArrayWriter) Write(p []byte) (n int, err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return 0, os.ErrClosed
+	}
+
+	var buf bytes.Buffer
+
+	if a.started {
+		buf.WriteString(",\n")
+	} else {
+		buf.WriteString("[\n")
+	}
+
+	buf.Write(bytes.TrimRight(p, "\n"))
+
+	if _, err = a.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	a.started = true
+
+	return len(p), nil
+}
+
+// Close terminates the array and closes the underlying writer if it is an io.Closer, leaving
+// os.Stdout and os.Stderr open as Logger.Close does. Further writes fail with os.ErrClosed.
+func (a *JSONArrayWriter) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.closed {
+		return nil
+	}
+
+	a.closed = true
+
+	end := "\n]\n"
+
+	if !a.started {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(a.w, end)
+
+	if f, ok := a.w.(*os.File); ok && (f == os.Stdout || f == os.Stderr) {
+		return err
+	}
+
+	if c, ok := a.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
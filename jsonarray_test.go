@@ -0,0 +1,88 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// closeRecorder is a buffer that records being closed.
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	for _, n := range []int{0, 1, 3} {
+		var out closeRecorder
+
+		w := NewJSONArrayWriter(&out)
+		l := NewWithWriter("app", DebugLevel, w)
+
+		for i := 0; i < n; i++ {
+			l.With(Data{"i": i}).Info("record")
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var records []WriteLog
+
+		if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+			t.Errorf("%d records: output is not a JSON array: %v\n%s", n, err, out.Bytes())
+			continue
+		}
+
+		if len(records) != n {
+			t.Errorf("%d records: array has %d elements", n, len(records))
+		}
+
+		if n == 0 && out.String() != "[]\n" {
+			t.Errorf("empty array written as %q", out.String())
+		}
+
+		if !out.closed {
+			t.Errorf("%d records: underlying writer not closed", n)
+		}
+
+		if _, err := w.Write([]byte("{}\n")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("write after Close = %v, want os.ErrClosed", err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Errorf("second Close = %v", err)
+		}
+	}
+}
+
+func TestJSONArrayWriterLeavesStdoutOpen(t *testing.T) {
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+
+	r, wr, err := os.Pipe()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+	defer wr.Close()
+
+	os.Stdout = wr
+
+	if err := NewJSONArrayWriter(os.Stdout).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wr.Write([]byte("still open\n")); err != nil {
+		t.Errorf("stdout was closed: %v", err)
+	}
+}
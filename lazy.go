@@ -0,0 +1,165 @@
+package log
+
+import "reflect"
+
+// Lazy is a data value computed only when a record is written. Use it for fields that are
+// expensive to build, so records filtered out by level, sampling or rate limits cost nothing:
+//
+//	logger.With(log.Data{"state": log.Lazy(func() any { return cache.Dump() })}).Debug("cache")
+//
+// A plain func() any works the same way. Lazy values are resolved before redaction and hooks
+// run, wherever they appear in the data, down to the limit set by SetMaxDepth. The same pass
+// replaces Loggable values with the result of their Log method, so a type stored in data is
+// written the way it chooses to describe itself rather than as its marshaled struct.
+type Lazy func() any
+
+// Placeholders written in place of data that the walk over a record's data will not descend
+// into. See SetMaxDepth.
+const (
+	maxDepthValue = "[truncated: max depth]"
+	cycleValue    = "[truncated: cycle]"
+)
+
+// resolveLazy replaces lazy and Loggable values in data, which must be owned by the caller,
+// with their results. Values nested deeper than limit are replaced by a placeholder, as are
+// maps that contain themselves, so the data handed on is finite however it was built. Nested
+// maps and slices are copied before being changed.
+func resolveLazy(data Data, limit int) {
+	walk := dataWalk{limit: limit, path: map[uintptr]bool{}}
+
+	for key, value := range data {
+		if resolved, ok := walk.value(value, 1); ok {
+			data[key] = resolved
+		}
+	}
+}
+
+// dataWalk holds the state of one resolveLazy pass. path records the maps being descended
+// into, so a map met again below itself is recognized as a cycle.
+type dataWalk struct {
+	limit int
+	path  map[uintptr]bool
+}
+
+// value returns the resolved form of value and whether it differs from value.
+func (w *dataWalk) value(value any, depth int) (any, bool) {
+	switch v := value.(type) {
+	case Lazy:
+		return w.resolved(v(), depth), true
+	case func() any:
+		return w.resolved(v(), depth), true
+	case Data:
+		resolved, ok := w.mapValue(v, depth)
+
+		if m, isMap := resolved.(map[string]any); ok && isMap {
+			return Data(m), true
+		}
+
+		return resolved, ok
+	case map[string]any:
+		return w.mapValue(v, depth)
+	case []any:
+		return w.list(v, depth)
+	case Loggable:
+		if isNilPointer(v) {
+			// as encoding/json writes a nil Marshaler, rather than calling Log on nothing
+			return nil, true
+		}
+
+		return w.resolved(Data(v.Log()), depth), true
+	}
+
+	return value, false
+}
+
+// isNilPointer reports whether value holds a nil pointer, such as a (*Fields)(nil) stored as
+// a Loggable.
+func isNilPointer(value any) bool {
+	v := reflect.ValueOf(value)
+
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// resolved resolves a value produced by a lazy or Loggable value, which may itself hold more.
+func (w *dataWalk) resolved(value any, depth int) any {
+	value, _ = w.value(value, depth+1)
+
+	return value
+}
+
+func (w *dataWalk) mapValue(m map[string]any, depth int) (any, bool) {
+	if len(m) == 0 {
+		return m, false
+	}
+
+	if depth > w.limit {
+		return maxDepthValue, true
+	}
+
+	id := reflect.ValueOf(m).Pointer()
+
+	if w.path[id] {
+		return cycleValue, true
+	}
+
+	w.path[id] = true
+	defer delete(w.path, id)
+
+	var set map[string]any
+
+	for key, value := range m {
+		resolved, ok := w.value(value, depth+1)
+
+		if !ok {
+			continue
+		}
+
+		if set == nil {
+			set = make(map[string]any, len(m))
+
+			for k, v := range m {
+				set[k] = v
+			}
+		}
+
+		set[key] = resolved
+	}
+
+	if set == nil {
+		return m, false
+	}
+
+	return set, true
+}
+
+func (w *dataWalk) list(s []any, depth int) (any, bool) {
+	if len(s) == 0 {
+		return s, false
+	}
+
+	if depth > w.limit {
+		return maxDepthValue, true
+	}
+
+	var list []any
+
+	for i, item := range s {
+		resolved, ok := w.value(item, depth+1)
+
+		if !ok {
+			continue
+		}
+
+		if list == nil {
+			list = append([]any(nil), s...)
+		}
+
+		list[i] = resolved
+	}
+
+	if list == nil {
+		return s, false
+	}
+
+	return list, true
+}
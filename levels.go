@@ -1,6 +1,10 @@
 package log
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
 
 type Level int
 
@@ -17,6 +21,9 @@ const (
 
 var defaultLevel = NoticeLevel
 
+// levelMutex guards the label tables, which RegisterLevel extends.
+var levelMutex sync.RWMutex
+
 var logLevelLabels = map[Level]string{
 	DebugLevel:     "debug",
 	InfoLevel:      "info",
@@ -39,13 +46,47 @@ var logLevelValues = map[string]Level{
 	"emergency": EmergencyLevel,
 }
 
+// RegisterLevel adds a custom level with its own label, such as "audit", so String, ToLevel
+// and ParseLevel know it and records can be written at it with Logf. The value orders the
+// level against the others: a logger writes it when value is at or above the logger's level.
+// Labels are matched ignoring case. Built-in levels cannot be redefined, and neither a value
+// nor a label may be registered twice. Register levels during program initialization.
+func RegisterLevel(value Level, label string) error {
+	label = strings.ToLower(label)
+
+	if label == "" {
+		return fmt.Errorf("log: level %d needs a label", value)
+	}
+
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+
+	if existing, ok := logLevelLabels[value]; ok {
+		return fmt.Errorf("log: level %d is already registered as %q", value, existing)
+	}
+
+	if existing, ok := logLevelValues[label]; ok {
+		return fmt.Errorf("log: label %q is already registered for level %d", label, existing)
+	}
+
+	logLevelLabels[value] = label
+	logLevelValues[label] = value
+
+	return nil
+}
+
 func (l Level) String() string {
+	levelMutex.RLock()
+	defer levelMutex.RUnlock()
 	return logLevelLabels[l]
 }
 
 func ToLevel(value string) Level {
 	value = strings.ToLower(value)
+
+	levelMutex.RLock()
 	level, ok := logLevelValues[value]
+	levelMutex.RUnlock()
 
 	if !ok {
 		return defaultLevel
@@ -53,3 +94,81 @@ func ToLevel(value string) Level {
 
 	return level
 }
+
+// ParseLevel converts a level label, ignoring case, into a Level. Unlike ToLevel it reports
+// unknown labels instead of falling back to the default level.
+func ParseLevel(value string) (Level, error) {
+	levelMutex.RLock()
+	level, ok := logLevelValues[strings.ToLower(value)]
+	levelMutex.RUnlock()
+
+	if !ok {
+		return defaultLevel, fmt.Errorf("log: unknown level %q", value)
+	}
+
+	return level, nil
+}
+
+// LevelNumbering selects whether records carry a numeric severity next to the level label,
+// and which scale it uses.
+type LevelNumbering int
+
+const (
+	// NoLevelNumber writes the level label only. This is the default.
+	NoLevelNumber LevelNumbering = iota
+	// NativeLevelNumber writes the Level value itself, from 0 for debug to 7 for emergency, so
+	// higher numbers are more severe.
+	NativeLevelNumber
+	// SyslogLevelNumber writes the RFC 5424 severity, from 7 for debug to 0 for emergency, so
+	// lower numbers are more severe.
+	SyslogLevelNumber
+	// OTelLevelNumber writes the OpenTelemetry severity number, from 5 for debug to 22 for
+	// emergency, so higher numbers are more severe and each level falls in the matching
+	// DEBUG, INFO, WARN, ERROR or FATAL range.
+	OTelLevelNumber
+)
+
+// otelSeverity maps the built-in levels onto the OpenTelemetry severity ranges.
+var otelSeverity = [...]int{
+	DebugLevel:     5,  // DEBUG
+	InfoLevel:      9,  // INFO
+	NoticeLevel:    10, // INFO2
+	WarningLevel:   13, // WARN
+	ErrorLevel:     17, // ERROR
+	CriticalLevel:  18, // ERROR2
+	AlertLevel:     21, // FATAL
+	EmergencyLevel: 22, // FATAL2
+}
+
+// SetLevelNumbering adds a level_num field holding the numeric severity of each record, for
+// processors that sort and filter on numbers rather than labels.
+func (l *Logger) SetLevelNumbering(numbering LevelNumbering) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.levelNumbering = numbering
+}
+
+// Number returns the severity of level on the scale n selects, for writers that map records
+// onto another severity scheme. It reports false for NoLevelNumber.
+func (n LevelNumbering) Number(level Level) (int, bool) {
+	if n == NativeLevelNumber {
+		return int(level), true
+	}
+
+	// custom levels outside the built-in range take the nearest built-in severity
+	switch {
+	case level < DebugLevel:
+		level = DebugLevel
+	case level > EmergencyLevel:
+		level = EmergencyLevel
+	}
+
+	switch n {
+	case SyslogLevelNumber:
+		return int(EmergencyLevel - level), true
+	case OTelLevelNumber:
+		return otelSeverity[level], true
+	}
+
+	return 0, false
+}
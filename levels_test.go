@@ -0,0 +1,124 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetLevelNumbering(t *testing.T) {
+	tests := []struct {
+		numbering LevelNumbering
+		level     Level
+		want      int
+	}{
+		{NativeLevelNumber, DebugLevel, 0},
+		{NativeLevelNumber, WarningLevel, 3},
+		{NativeLevelNumber, EmergencyLevel, 7},
+		{SyslogLevelNumber, DebugLevel, 7},
+		{SyslogLevelNumber, ErrorLevel, 3},
+		{SyslogLevelNumber, EmergencyLevel, 0},
+		{SyslogLevelNumber, EmergencyLevel + 3, 0},
+		{OTelLevelNumber, DebugLevel, 5},
+		{OTelLevelNumber, NoticeLevel, 10},
+		{OTelLevelNumber, CriticalLevel, 18},
+		{OTelLevelNumber, EmergencyLevel, 22},
+		{OTelLevelNumber, EmergencyLevel + 3, 22},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+
+		l := NewWithWriter("app", DebugLevel, &buf)
+		l.SetLevelNumbering(tt.numbering)
+		l.Logf(tt.level, "record")
+
+		records := decodeRecords(t, &buf)
+
+		if len(records) != 1 || records[0].LevelNum == nil {
+			t.Errorf("numbering %d, level %d: no level_num in %s", tt.numbering, tt.level, buf.Bytes())
+			continue
+		}
+
+		if got := *records[0].LevelNum; got != tt.want {
+			t.Errorf("numbering %d, level %d: level_num = %d, want %d", tt.numbering, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestNoLevelNumber(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetLevelNumbering(SyslogLevelNumber)
+	l.SetLevelNumbering(NoLevelNumber)
+	l.Error("record")
+
+	if bytes.Contains(buf.Bytes(), []byte("level_num")) {
+		t.Errorf("NoLevelNumber wrote %s", buf.Bytes())
+	}
+}
+
+// Levels registered by TestRegisterLevel. Registration is process wide, so the values and
+// labels are ones no other test uses.
+const (
+	testTraceLevel Level = -20
+	testAuditLevel Level = 20
+)
+
+func TestRegisterLevel(t *testing.T) {
+	// registered once, so the test can run repeatedly in one process with -count
+	if testAuditLevel.String() == "" {
+		if err := RegisterLevel(testTraceLevel, "TestTrace"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := RegisterLevel(testAuditLevel, "testaudit"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if testTraceLevel.String() != "testtrace" || ToLevel("TESTTRACE") != testTraceLevel {
+		t.Errorf("trace level = %q, ToLevel = %d", testTraceLevel, ToLevel("TESTTRACE"))
+	}
+
+	if level, err := ParseLevel("TestAudit"); err != nil || level != testAuditLevel {
+		t.Errorf("ParseLevel(TestAudit) = %d, %v", level, err)
+	}
+
+	for _, tt := range []struct {
+		value Level
+		label string
+	}{
+		{testAuditLevel, "other"},
+		{Level(21), "testaudit"},
+		{InfoLevel, "information"},
+		{Level(22), "INFO"},
+		{Level(23), ""},
+	} {
+		if err := RegisterLevel(tt.value, tt.label); err == nil {
+			t.Errorf("RegisterLevel(%d, %q) succeeded", tt.value, tt.label)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.Logf(testTraceLevel, "below debug")
+	l.Logf(testAuditLevel, "above emergency")
+
+	l.SetLevel(EmergencyLevel)
+	l.Logf(testAuditLevel, "still written")
+
+	records := decodeRecords(t, &buf)
+	got := make([]string, len(records))
+
+	for i, rec := range records {
+		got[i] = rec.Level + ": " + rec.Msg
+	}
+
+	want := []string{"testaudit: above emergency", "testaudit: still written"}
+
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
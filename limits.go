@@ -0,0 +1,115 @@
+package log
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncatedSuffix marks a string field shortened by SetMaxFieldBytes.
+const truncatedSuffix = "…(truncated)"
+
+// SetMaxFieldBytes caps the length of string values in data, including those in nested maps
+// and slices. Longer values are cut to n bytes, on a character boundary, and suffixed with
+// "…(truncated)"; the placeholders left by SetMaxDepth are kept whole. Zero disables the
+// limit.
+func (l *Logger) SetMaxFieldBytes(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.maxFieldBytes = n
+}
+
+// SetMaxRecordBytes caps the size of an encoded record. A record that comes out larger is
+// encoded again without its data, which is replaced by a log_truncated marker, so output stays
+// bounded whatever the caller logs. Zero disables the limit. The limit applies to the bytes
+// handed to the writer, so it has no effect on writers implementing RecordWriter.
+func (l *Logger) SetMaxRecordBytes(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.maxRecordBytes = n
+}
+
+// truncateData shortens string values in data, which must be owned by the caller. Nested maps
+// and slices are copied before being shortened so values shared with the application are
+// never modified.
+func truncateData(data Data, n, limit int) {
+	for key, value := range data {
+		data[key] = truncateValue(value, n, 1, limit)
+	}
+}
+
+func truncateValue(value any, n, depth, limit int) any {
+	if s, ok := value.(string); ok {
+		if s == maxDepthValue || s == cycleValue {
+			return s
+		}
+
+		return truncateString(s, n)
+	}
+
+	// a map or slice this deep has already been replaced by the depth placeholder, so the
+	// check only guards against descending forever
+	if depth > limit {
+		return value
+	}
+
+	switch v := value.(type) {
+	case Data:
+		return Data(truncateMap(v, n, depth, limit))
+	case map[string]any:
+		return truncateMap(v, n, depth, limit)
+	case []any:
+		list := make([]any, len(v))
+
+		for i, item := range v {
+			list[i] = truncateValue(item, n, depth+1, limit)
+		}
+
+		return list
+	}
+
+	return value
+}
+
+func truncateMap(m map[string]any, n, depth, limit int) map[string]any {
+	set := make(map[string]any, len(m))
+
+	for key, value := range m {
+		set[key] = truncateValue(value, n, depth+1, limit)
+	}
+
+	return set
+}
+
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	cut := n
+
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + truncatedSuffix
+}
+
+// capRecord re-encodes out without its data when data exceeds limit bytes. If the message alone
+// is still too large it is truncated as well.
+func capRecord(formatter Formatter, out WriteLog, data []byte, limit int) ([]byte, error) {
+	if limit <= 0 || len(data) <= limit {
+		return data, nil
+	}
+
+	out.Data = Data{"log_truncated": fmt.Sprintf("record of %d bytes exceeded the %d byte limit", len(data), limit)}
+
+	capped, err := formatter.Format(out)
+
+	if err != nil || len(capped) <= limit {
+		return capped, err
+	}
+
+	out.Msg = truncateString(out.Msg, limit/2)
+
+	return formatter.Format(out)
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSetMaxFieldBytes(t *testing.T) {
+	nested := Data{"body": "0123456789", "short": "abc"}
+	list := []any{"0123456789", Data{"deep": "0123456789"}, 42}
+
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{
+		"msg":     "0123456789",
+		"exact":   "01234",
+		"accents": "héllo",
+		"nested":  nested,
+		"list":    list,
+		"count":   1234567890,
+	})
+	l.SetMaxFieldBytes(5)
+
+	cut := "01234" + truncatedSuffix
+	want := map[string]any{
+		"msg":     cut,
+		"exact":   "01234",
+		"accents": "héll" + truncatedSuffix,
+		"nested":  map[string]any{"body": cut, "short": "abc"},
+		"list":    []any{cut, map[string]any{"deep": cut}, 42.0},
+		"count":   1234567890.0,
+	}
+
+	if got := recordData(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+
+	if nested["body"] != "0123456789" || list[0] != "0123456789" {
+		t.Errorf("truncation changed the caller's values: %v %v", nested, list)
+	}
+
+	l.SetMaxFieldBytes(2)
+
+	if got := recordData(t, l)["accents"]; got != "h"+truncatedSuffix {
+		t.Errorf("accents = %q, want the cut moved back to a character boundary", got)
+	}
+
+	l.SetMaxFieldBytes(0)
+
+	if got := recordData(t, l)["msg"]; got != "0123456789" {
+		t.Errorf("with no limit, msg = %q", got)
+	}
+}
+
+func TestSetMaxFieldBytesNested(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{
+		"a": Data{"b": "0123456789", "c": Data{"d": "0123456789"}},
+	})
+	l.SetMaxFieldBytes(5)
+	l.SetMaxDepth(2)
+
+	cut := "01234" + truncatedSuffix
+	want := map[string]any{"a": map[string]any{"b": cut, "c": map[string]any{"d": cut}}}
+
+	if got := recordData(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+
+	l.SetMaxDepth(1)
+	want = map[string]any{"a": map[string]any{"b": cut, "c": maxDepthValue}}
+
+	if got := recordData(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("with depth 1, data = %v, want %v", got, want)
+	}
+}
@@ -1,29 +1,85 @@
 package log
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Logger struct {
-	level Level
-	app   string
-	data  Data
-	mutex sync.Mutex
-	Out   io.Writer
+	level      atomic.Int32
+	app        string
+	host       string
+	pid        int
+	callerSkip int
+	nop        bool
+	groups     []string
+	fields     *fieldSet
+	mutex      sync.Mutex
+	opts       options
+	skewStop   chan struct{}
+
+	signalOnce    sync.Once
+	signalRaised  bool
+	signalRestore Level
+
+	Out io.Writer
+}
+
+// options holds the settings a logger passes on to the loggers derived from it. It is copied
+// as a whole, so slice and map fields are always replaced, never modified in place.
+type options struct {
+	mergeStrategy  MergeStrategy
+	format         Format
+	formatter      Formatter
+	fieldOrder     []string
+	fieldNames     map[string]string
+	keyConvention  KeyConvention
+	redactKeys     map[string]bool
+	hooks          []func(*WriteLog)
+	maxFieldBytes  int
+	maxRecordBytes int
+	emitters       []*emitter
+	errorHandler   func(error)
+	sampler        *sampler
+	rateLimiter    *rateLimiter
+	levelWriters   []levelWriter
+	noSource       bool
+	sourceLevel    Level
+	levelNumbering LevelNumbering
+	indentPrefix   string
+	indent         string
+	metrics        Metrics
+	baseFields     map[string]any
+	clock          func() time.Time
+	maxDepth       int
+	always         bool
+	deduper        *deduper
+	skipPackages   []string
+	requireMessage bool
+	levelCallbacks []levelCallback
+	sinks          []sink
+	splitSource    bool
 }
 
 type Loggable interface {
 	Log() map[string]any
 }
 
+// LoggableRecord is implemented by values that describe an entire log entry rather than just
+// its data. See Log.
+type LoggableRecord interface {
+	Record() WriteLog
+}
+
 type Data map[string]any
 
 func (d Data) Log() map[string]any {
@@ -31,190 +87,1036 @@ func (d Data) Log() map[string]any {
 }
 
 // New creates a new Logger instance with a specific name and the minimum log level to write.
-func New(app string, logLevel Level) *Logger {
-	return &Logger{
-		level: logLevel,
-		app:   app,
-		Out:   &stdOutWriter{},
+// base optionally sets fields present in every record; see SetBaseFields.
+func New(app string, logLevel Level, base ...Loggable) *Logger {
+	l := &Logger{
+		app: app,
+		Out: &stdOutWriter{},
+	}
+
+	l.level.Store(int32(logLevel))
+	l.opts.baseFields = baseFields(base)
+
+	return l
+}
+
+// SetBaseFields replaces the logger's base fields: static data such as a version or region
+// that every record carries. Base fields are kept apart from the data added by With, so a
+// later With of the same key neither removes nor slice-ifies them; the base value is always
+// the one written. Loggers derived from l afterwards share them. Calling it with nothing
+// removes them.
+func (l *Logger) SetBaseFields(base ...Loggable) {
+	fields := baseFields(base)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.baseFields = fields
+}
+
+// baseFields flattens base into a single map, later values winning, or nil when empty.
+func baseFields(base []Loggable) map[string]any {
+	var fields map[string]any
+
+	for _, node := range base {
+		if node == nil {
+			continue
+		}
+
+		for key, value := range node.Log() {
+			if fields == nil {
+				fields = make(map[string]any)
+			}
+
+			fields[key] = value
+		}
 	}
+
+	return fields
+}
+
+// NewWithWriter creates a Logger like New that writes to w. A nil w falls back to stdout.
+func NewWithWriter(app string, logLevel Level, w io.Writer, base ...Loggable) *Logger {
+	l := New(app, logLevel, base...)
+
+	if w != nil {
+		l.Out = w
+	}
+
+	return l
+}
+
+// NewWithWriterE is NewWithWriter for callers that would rather treat a nil writer as a
+// configuration mistake than log to stdout.
+func NewWithWriterE(app string, logLevel Level, w io.Writer, base ...Loggable) (*Logger, error) {
+	if w == nil {
+		return nil, errors.New("log: nil writer")
+	}
+
+	return NewWithWriter(app, logLevel, w, base...), nil
+}
+
+// NewNop returns a logger that discards everything. Its records are rejected before any
+// formatting, caller lookup or encoding takes place, which makes it suitable for benchmarks
+// and for libraries that accept a *Logger but should stay silent. Loggers derived from it
+// are no-ops as well, and SetLevel does not turn it on. Install it with SetDefault to silence
+// the package level functions and FromContext for contexts that carry no logger.
+func NewNop() *Logger {
+	l := &Logger{
+		nop: true,
+		Out: io.Discard,
+	}
+
+	l.level.Store(int32(EmergencyLevel))
+
+	return l
+}
+
+// SetOutput replaces the writer records are sent to. A nil w restores stdout.
+func (l *Logger) SetOutput(w io.Writer) {
+	if w == nil {
+		w = &stdOutWriter{}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.Out = w
+}
+
+type levelWriter struct {
+	min Level
+	w   io.Writer
+}
+
+// SetLevelWriter additionally sends records at or above minLevel to w, after they have been
+// written to the primary writer. Use it to copy errors to a separate sink. Each call adds a
+// route; routes are tried in the order they were added.
+func (l *Logger) SetLevelWriter(minLevel Level, w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	routes := l.opts.levelWriters
+	l.opts.levelWriters = append(routes[:len(routes):len(routes)], levelWriter{min: minLevel, w: w})
 }
 
 func (l *Logger) AppName() string {
 	return l.app
 }
 
+// SetLevel changes the minimum level this logger writes. It is safe to call while other
+// goroutines are logging.
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// PushLevel sets the minimum level to level and returns a function that puts back the level
+// in effect before the call, for turning up verbosity around one code path:
+//
+//	restore := logger.PushLevel(log.DebugLevel)
+//	defer restore()
+//
+// Pushes nest when restored in reverse order. Calling restore more than once has no further
+// effect.
+func (l *Logger) PushLevel(level Level) (restore func()) {
+	prev := l.level.Swap(int32(level))
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			l.level.Store(prev)
+		})
+	}
+}
+
+// Level returns the minimum level this logger writes. The level is read atomically so that
+// records below it are discarded without taking the logger lock.
+func (l *Logger) Level() Level {
+	return Level(l.level.Load())
+}
+
+// Enabled reports whether a record at level would be written. Use it to skip building
+// expensive data for records that would be filtered out anyway:
+//
+//	if logger.Enabled(log.DebugLevel) {
+//		logger.With(expensiveData()).Debug("cache state")
+//	}
+func (l *Logger) Enabled(level Level) bool {
+	return !l.nop && level >= l.Level()
+}
+
 // Debug records detailed debug information about the data.
 func (l *Logger) Debug(msg string, args ...any) {
-	l.output(2, DebugLevel, fmt.Sprintf(msg, args...))
+	l.output(2, DebugLevel, msg, args)
 }
 
 // Info records interesting events. Examples: User logs in, SQL logs, etc.
 func (l *Logger) Info(msg string, args ...any) {
-	l.output(2, InfoLevel, fmt.Sprintf(msg, args...))
+	l.output(2, InfoLevel, msg, args)
 }
 
 // Notice records normal but significant events.
 func (l *Logger) Notice(msg string, args ...any) {
-	l.output(2, NoticeLevel, fmt.Sprintf(msg, args...))
+	l.output(2, NoticeLevel, msg, args)
 }
 
 // Warn records exceptional occurrences that are not errors. Examples: Use of deprecated APIs,
 // poor use of an API, undesirable things that are not necessarily wrong.
 func (l *Logger) Warn(msg string, args ...any) {
-	l.output(2, WarningLevel, fmt.Sprintf(msg, args...))
+	l.output(2, WarningLevel, msg, args)
 }
 
 // Error records runtime errors that do not require immediate action but should typically be logged and monitored.
 func (l *Logger) Error(msg string, args ...any) {
-	l.output(2, ErrorLevel, fmt.Sprintf(msg, args...))
+	l.output(2, ErrorLevel, msg, args)
 }
 
 // Critical records critical conditions. Example: Application service unavailable, unexpected exception.
 func (l *Logger) Critical(msg string, args ...any) {
-	l.output(2, CriticalLevel, fmt.Sprintf(msg, args...))
+	l.output(2, CriticalLevel, msg, args)
 }
 
 // Alert records exceptions where action MUST be taken immediately. Example: website down, database unavailable, etc.
 // This should wake someone up.
 func (l *Logger) Alert(msg string, args ...any) {
-	l.output(2, AlertLevel, fmt.Sprintf(msg, args...))
+	l.output(2, AlertLevel, msg, args)
 }
 
 // Emergency records instances where the system is totally unusable.
 func (l *Logger) Emergency(msg string, args ...any) {
-	l.output(2, EmergencyLevel, fmt.Sprintf(msg, args...))
+	l.output(2, EmergencyLevel, msg, args)
 }
 
 // Fatal writes and emergency log and then calls os.Exit(1).
 func (l *Logger) Fatal(msg string, args ...any) {
-	l.output(2, EmergencyLevel, fmt.Sprintf(msg, args...))
+	l.output(2, EmergencyLevel, msg, args)
 	os.Exit(1)
 }
 
-// With saves specific data to be written out to the remote service when the level is called.
-func (l *Logger) With(data ...Loggable) *Logger {
-	// set will allow us to detect when a key has already been created with a value
-	// and change the value to a slice of values if the key is presented again for logging.
-	// logger := log.New(...).With(log.Data{"key": "v1"}) => {... "key":"v1" ...}
-	// logger.With(log.Data{"key": "v2"})                 => {... "key":["v1","v2"] ...}
-	set := make(map[string]any)
+// Logf writes a record at level, which may be a custom level added with RegisterLevel.
+func (l *Logger) Logf(level Level, msg string, args ...any) {
+	l.output(2, level, msg, args)
+}
 
-	// @IMPROVE Right now this implementation is memory hungry. There is much room for improvement.
-	for key, value := range l.data {
-		set[key] = value
+// Log writes a record at level whose data comes from v. When v also implements
+// LoggableRecord, Record describes the whole entry instead: its message, data and any
+// top-level field it sets, such as App or Time, are used in place of the logger's, and Log
+// is not called. The level is always the one given here.
+func (l *Logger) Log(level Level, v Loggable) {
+	if !l.Enabled(level) || v == nil {
+		return
 	}
 
-	for _, node := range data {
-		for key, value := range node.Log() {
-			// do we have a current key already?
-			if current, ok := set[key]; ok {
-				// is the current value already a slice?
-				if s, ok := current.([]any); ok {
-					// append to old slice
-					s = append(s, value)
-					set[key] = s
-					continue
-				}
-
-				// create a new slice since we have the key already but a new value
-				set[key] = []any{current, value}
-				continue
+	// typed fields go straight into the record, without building a map of their own first
+	if f, ok := v.(*Fields); ok {
+		if f != nil {
+			l.emitFields(2, level, WriteLog{}, f)
+		}
+
+		return
+	}
+
+	var rec WriteLog
+
+	if r, ok := v.(LoggableRecord); ok {
+		rec = r.Record()
+	} else {
+		rec.Data = v.Log()
+	}
+
+	l.emit(2, level, rec)
+}
+
+// LogAt writes a record at level stamped with t instead of the current time, for replaying or
+// backfilling events and for deterministic output in tests. A zero t means now.
+func (l *Logger) LogAt(t time.Time, level Level, msg string, args ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	l.emit(2, level, WriteLog{Time: t, Msg: sprintf(msg, args)})
+}
+
+// With returns a copy of the logger that adds data to every record it writes, for as long as
+// the copy is in use; l itself is unaffected. For data that belongs to a single record, call
+// the level method on the copy directly: logger.With(data).Info("saved"). When a key is
+// presented again, by the same or a later call, its value becomes a slice of all values given
+// for it.
+func (l *Logger) With(data ...Loggable) *Logger {
+	child := l.clone()
+
+	if len(l.groups) > 0 {
+		nodes := make([]Loggable, 0, len(data))
+
+		for _, node := range data {
+			if node != nil {
+				nodes = append(nodes, Data(l.grouped(node.Log())))
 			}
+		}
 
-			// create a new entry
-			set[key] = value
+		data = nodes
+	}
+
+	child.fields = newFieldSet(child.fields, data)
+
+	return child
+}
+
+// WithIf is With when cond holds. Otherwise it returns l itself, without copying it, so
+// settings changed on the result apply to l as well.
+func (l *Logger) WithIf(cond bool, data ...Loggable) *Logger {
+	if !cond {
+		return l
+	}
+
+	return l.With(data...)
+}
+
+// WithLevel is With when records at min are enabled, for diagnostic data that is only worth
+// attaching when, say, debug output is on:
+//
+//	logger.WithLevel(log.DebugLevel, log.Data{"query": q}).Info("search")
+//
+// Otherwise it returns l itself, as WithIf does.
+func (l *Logger) WithLevel(min Level, data ...Loggable) *Logger {
+	return l.WithIf(l.Enabled(min), data...)
+}
+
+// WithFields is With for a plain map, sparing callers the Data conversion:
+//
+//	logger.WithFields(map[string]any{"user": id}).Info("login")
+func (l *Logger) WithFields(fields map[string]any) *Logger {
+	return l.With(Data(fields))
+}
+
+// WithField is With for a single field. Repeating a key merges exactly as With does.
+func (l *Logger) WithField(key string, value any) *Logger {
+	return l.With(Data{key: value})
+}
+
+// workerCounter hands out the ids returned by NextWorkerID.
+var workerCounter atomic.Int64
+
+// NextWorkerID returns a process wide unique worker id, "1", "2" and so on, for pools that do
+// not number their workers themselves.
+func NextWorkerID() string {
+	return strconv.FormatInt(workerCounter.Add(1), 10)
+}
+
+// WithWorker returns a copy of the logger whose records carry id as worker, so lines written
+// by a pool can be told apart:
+//
+//	wlog := logger.WithWorker(log.NextWorkerID())
+func (l *Logger) WithWorker(id string) *Logger {
+	return l.With(Data{"worker": id})
+}
+
+// Named returns a copy of the logger whose app name has suffix appended with a dot, so
+// components can be told apart: New("api", ...).Named("users") writes "app":"api.users". If
+// the logger has no app name yet, suffix becomes the name.
+func (l *Logger) Named(suffix string) *Logger {
+	child := l.clone()
+
+	if child.app == "" {
+		child.app = suffix
+	} else {
+		child.app += "." + suffix
+	}
+
+	return child
+}
+
+// WithApp returns a copy of the logger that writes app as the app name, replacing rather than
+// extending it as Named does. The data, level and writer of l are kept.
+func (l *Logger) WithApp(app string) *Logger {
+	child := l.clone()
+	child.app = app
+
+	return child
+}
+
+// ClearData returns a copy of the logger without any of the data accumulated by With. The app,
+// level and writer are kept and l itself is unaffected. Use it to shed request scoped fields
+// once a unit of work is done.
+func (l *Logger) ClearData() *Logger {
+	child := l.clone()
+	child.fields = nil
+
+	return child
+}
+
+// WithFormat returns a copy of the logger that encodes records with one of the built-in
+// formats while keeping the data, level and writer of l. Writers that implement RecordWriter
+// render records themselves, so the format has no effect on them.
+func (l *Logger) WithFormat(format Format) *Logger {
+	child := l.clone()
+	child.opts.format = format
+	child.opts.formatter = nil
+
+	return child
+}
+
+// WithCallerSkip returns a copy of the logger that skips n additional stack frames when
+// capturing the source of a record. Wrappers around the logger use it so the reported source
+// is their caller rather than the wrapper itself:
+//
+//	var reqLog = logger.WithCallerSkip(1)
+//
+//	func logRequest(r *http.Request) {
+//		reqLog.Info("%s %s", r.Method, r.URL.Path) // Src points at the caller of logRequest
+//	}
+func (l *Logger) WithCallerSkip(n int) *Logger {
+	child := l.clone()
+	child.callerSkip += n
+
+	return child
+}
+
+// SetCallerSkipPackages makes the source of records the first caller outside the packages
+// whose import paths are given, for facades that wrap the logger with a varying number of
+// frames:
+//
+//	logger.SetCallerSkipPackages("github.com/acme/platform/logging")
+//
+// A path also covers the packages below it. The walk starts where WithCallerSkip would
+// place the source. Calling it again replaces the list; calling it with no paths restores
+// the plain caller. Loggers derived from l afterwards skip the same packages.
+func (l *Logger) SetCallerSkipPackages(paths ...string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.skipPackages = append([]string(nil), paths...)
+}
+
+// callerOutside returns the source of the first frame from skip on whose function is not in
+// one of the packages under paths.
+func callerOutside(skip int, paths []string) Src {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+1, pcs)])
+
+	for {
+		frame, more := frames.Next()
+
+		if frame.PC != 0 && !inPackages(frame.Function, paths) {
+			src := Src{File: frame.File, Line: frame.Line}
+			src.TruncateFile()
+
+			return src
+		}
+
+		if !more {
+			return Src{File: "???"}
 		}
 	}
+}
 
-	return &Logger{
-		app:   l.app,
-		level: l.level,
-		data:  set,
-		Out:   l.Out,
+// inPackages reports whether function, as named by runtime.Frame, belongs to a package at or
+// below one of paths.
+func inPackages(function string, paths []string) bool {
+	for _, path := range paths {
+		if !strings.HasPrefix(function, path) {
+			continue
+		}
+
+		if rest := function[len(path):]; rest == "" || rest[0] == '.' || rest[0] == '/' {
+			return true
+		}
 	}
+
+	return false
 }
 
-// output creates the structured log and sends it to the writer.
-func (l *Logger) output(callDepth int, level Level, msg string) {
-	var out WriteLog
-	var ok bool
+// Clone returns an independent copy of the logger. The level, app name, accumulated data,
+// hooks and other settings are copied, so changing them on the clone leaves l untouched and
+// the reverse. The writer is shared: both loggers write to the same Out until one of them is
+// given another with SetOutput, and a writer that is not safe for concurrent use must be
+// guarded by the caller. Samplers and rate limits set on l keep counting across both.
+func (l *Logger) Clone() *Logger {
+	child := l.clone()
+	data := Data{}
+
+	child.fields.merge(data, child.opts.mergeStrategy)
+	child.fields = newFieldSet(nil, []Loggable{data})
+
+	return child
+}
+
+// clone returns a copy of l that shares its writer and configuration. It takes the lock so
+// the copy is consistent with concurrent calls that change l, such as SetOutput.
+func (l *Logger) clone() *Logger {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
-	if level < l.level {
-		l.mutex.Lock()
-		defer l.mutex.Unlock()
-		l.data = make(map[string]any)
+	child := &Logger{
+		app:        l.app,
+		host:       l.host,
+		pid:        l.pid,
+		callerSkip: l.callerSkip,
+		nop:        l.nop,
+		groups:     l.groups,
+		fields:     l.fields,
+		opts:       l.opts,
+		Out:        l.Out,
+	}
+
+	child.level.Store(l.level.Load())
+
+	return child
+}
+
+// OnEmit registers fn to be called with every record this logger writes. Unlike a writer,
+// fn is a read-only observer: it receives its own deep copy of each record once the record has
+// been handed to the writer. fn runs on a goroutine of its own, one record at a time in the
+// order they were written, so a slow observer only holds up logging once it has fallen 1024
+// records behind; from then on logging waits for it rather than letting it miss records.
+// Flush waits until fn has seen every record written before the call.
+func (l *Logger) OnEmit(fn func(WriteLog)) {
+	e := &emitter{fn: fn, queue: make(chan emitRequest, emitQueueSize)}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// force a copy so loggers created by With before this call keep their own list
+	l.opts.emitters = append(l.opts.emitters[:len(l.opts.emitters):len(l.opts.emitters)], e)
+}
+
+// emitQueueSize is the number of records an OnEmit observer may fall behind by before
+// logging waits for it.
+const emitQueueSize = 1024
+
+// emitter feeds the records for one OnEmit observer to the goroutine that calls it, which is
+// started with the first record.
+type emitter struct {
+	fn    func(WriteLog)
+	queue chan emitRequest
+	start sync.Once
+}
+
+// emitRequest carries either a record or, for wait, a channel closed once every record queued
+// before it has been observed.
+type emitRequest struct {
+	rec     WriteLog
+	flushed chan struct{}
+}
+
+func (e *emitter) send(rec WriteLog) {
+	e.start.Do(func() { go e.run() })
+	e.queue <- emitRequest{rec: rec}
+}
+
+// wait returns once the observer has seen every record sent before the call.
+func (e *emitter) wait() {
+	e.start.Do(func() { go e.run() })
+
+	flushed := make(chan struct{})
+	e.queue <- emitRequest{flushed: flushed}
+	<-flushed
+}
+
+func (e *emitter) run() {
+	for req := range e.queue {
+		if req.flushed != nil {
+			close(req.flushed)
+			continue
+		}
+
+		e.fn(req.rec)
+	}
+}
+
+type levelCallback struct {
+	min Level
+	fn  func(WriteLog)
+}
+
+// OnLevel registers fn to be called with every record at level or above that this logger
+// writes, to page someone on Alert and Emergency records:
+//
+//	logger.OnLevel(log.AlertLevel, func(rec log.WriteLog) { go pager.Trigger(rec.Msg, rec.Data) })
+//
+// fn runs synchronously after the record has been handed to the writers, even when they
+// failed, outside the logger lock and with its own copy of the record; start a goroutine from
+// fn for slow deliveries. Each call adds a callback, and loggers derived from l afterwards
+// call the same ones.
+func (l *Logger) OnLevel(level Level, fn func(WriteLog)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	callbacks := l.opts.levelCallbacks
+	l.opts.levelCallbacks = append(callbacks[:len(callbacks):len(callbacks)], levelCallback{min: level, fn: fn})
+}
+
+// SetSplitSource writes the package directory of the source apart from the filename, as
+// "src":{"package":"model","file":"user.go","line":12} instead of the default
+// "src":{"file":"model/user.go","line":12}, so records can be queried by package.
+func (l *Logger) SetSplitSource(enabled bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.splitSource = enabled
+}
+
+// SetIncludeSource controls whether records carry the file and line they were logged from.
+// Disabling it skips the caller lookup entirely and omits src from the output, which suits
+// records whose source would be meaningless, such as access logs written by middleware.
+func (l *Logger) SetIncludeSource(enabled bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.noSource = !enabled
+}
+
+// SetSourceLevel limits source capture to records at or above min, so cheap, frequent records
+// such as debug and info skip the caller lookup while warnings and errors still say where they
+// came from. The default, DebugLevel, captures the source of every record. SetIncludeSource
+// with false turns capture off regardless of the level.
+func (l *Logger) SetSourceLevel(min Level) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.sourceLevel = min
+}
+
+// SetIncludeHost adds the hostname and process id as top level host and pid fields on every
+// record. Both are looked up once, when the option is enabled.
+func (l *Logger) SetIncludeHost(enabled bool) {
+	var host string
+	var pid int
+
+	if enabled {
+		host, _ = os.Hostname()
+		pid = os.Getpid()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.host = host
+	l.pid = pid
+}
+
+// sprintf formats msg with args. A message logged without args is used verbatim so that a
+// literal percent sign, as in "disk 90% full", is not treated as a formatting verb.
+func sprintf(msg string, args []any) string {
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+// output creates the structured log and sends it to the writer. msg is only formatted with
+// args once the record is known to pass the level check.
+func (l *Logger) output(callDepth int, level Level, msg string, args []any) {
+	// the level check is lock free so filtered calls never contend on the mutex
+	if !l.Enabled(level) {
 		return
 	}
 
-	out.Time = time.Now().UTC()
+	l.emit(callDepth+1, level, WriteLog{Msg: sprintf(msg, args)})
+}
 
-	_, out.Src.File, out.Src.Line, ok = runtime.Caller(callDepth)
+// emit completes rec with the logger's app, data and the caller's source and writes it. Fields
+// already set in rec take precedence, and its data is merged over the accumulated data. The
+// caller has checked the level.
+func (l *Logger) emit(callDepth int, level Level, rec WriteLog) {
+	l.emitFields(callDepth+1, level, rec, nil)
+}
 
-	if !ok {
-		out.Src.File = "???"
-		out.Src.Line = 0
-	} else {
-		out.Src.TruncateFile()
+// emitFields is emit for a record whose data also includes typed, when it is not nil.
+func (l *Logger) emitFields(callDepth int, level Level, rec WriteLog, typed *Fields) {
+	var out WriteLog
+	var ok bool
+
+	if !l.messageAllowed(rec.Msg) {
+		return
 	}
 
+	if !l.exempt(level) && (!l.sampled(level, rec.Msg) || !l.withinRate() || !l.deduped(level, rec.Msg)) {
+		return
+	}
+
+	out.Time = rec.Time.UTC()
 	out.Level = level.String()
-	out.Msg = msg
+	out.Msg = rec.Msg
 	out.Data = map[string]any{}
 
 	l.mutex.Lock()
 
 	out.App = l.app
+	out.Host = l.host
+	out.PID = l.pid
+
+	l.fields.merge(out.Data, l.opts.mergeStrategy)
+
+	for key, value := range l.grouped(rec.Data) {
+		mergeField(out.Data, key, value, l.opts.mergeStrategy, 0)
+	}
+
+	if typed != nil && len(l.groups) > 0 {
+		for key, value := range l.grouped(typed.Log()) {
+			mergeField(out.Data, key, value, l.opts.mergeStrategy, 0)
+		}
+	} else if typed != nil {
+		typed.merge(out.Data, l.opts.mergeStrategy)
+	}
 
-	for key, value := range l.data {
+	for key, value := range l.opts.baseFields {
 		out.Data[key] = value
 	}
 
-	l.data = make(map[string]any)
+	noSource := l.opts.noSource || level < l.opts.sourceLevel
+	clock := l.opts.clock
+	skipPackages := l.opts.skipPackages
 
 	l.mutex.Unlock()
 
-	data, err := json.Marshal(out)
+	if rec.Time.IsZero() {
+		out.Time = now(clock)
+	}
+
+	if rec.App != "" {
+		out.App = rec.App
+	}
+
+	if rec.Host != "" {
+		out.Host = rec.Host
+	}
+
+	if rec.PID != 0 {
+		out.PID = rec.PID
+	}
+
+	if !rec.Src.IsZero() {
+		out.Src = rec.Src
+	} else if !noSource && len(skipPackages) > 0 {
+		out.Src = callerOutside(callDepth+l.callerSkip+1, skipPackages)
+	} else if !noSource {
+		_, out.Src.File, out.Src.Line, ok = runtime.Caller(callDepth + l.callerSkip)
+
+		if !ok {
+			out.Src.File = "???"
+			out.Src.Line = 0
+		} else {
+			out.Src.TruncateFile()
+		}
+	}
+
+	l.write(level, out)
+}
+
+// writeInternal writes a record produced by the logger itself, such as a sampling summary.
+// It bypasses filtering and carries no source location.
+func (l *Logger) writeInternal(level Level, msg string, data Data) {
+	rec := WriteLog{
+		Level: level.String(),
+		Msg:   msg,
+		Data:  data,
+	}
+
+	l.mutex.Lock()
+	clock := l.opts.clock
+	rec.App = l.app
+	rec.Host = l.host
+	rec.PID = l.pid
+	l.mutex.Unlock()
+
+	rec.Time = now(clock)
+
+	l.write(level, rec)
+}
+
+// write encodes a finished record and hands it to the writer. Records built outside of
+// output, such as those passed to Replay, enter here.
+func (l *Logger) write(level Level, out WriteLog) {
+	l.mutex.Lock()
+	opts := l.opts
+	w := l.Out
+	l.mutex.Unlock()
+
+	// Out is exported and may have been cleared directly
+	if w == nil {
+		w = &stdOutWriter{}
+	}
+
+	resolveLazy(out.Data, opts.depthLimit())
+
+	if opts.splitSource && out.Src.Package == "" {
+		out.Src.SplitPackage()
+	}
+
+	if num, ok := opts.levelNumbering.Number(level); ok {
+		out.LevelNum = &num
+	}
+
+	if opts.keyConvention == SnakeCase {
+		for key, value := range out.Data {
+			out.Data[key] = snakeCaseValue(value, opts.depthLimit())
+		}
+	}
+
+	if len(opts.redactKeys) > 0 {
+		redactData(out.Data, opts.redactKeys, opts.depthLimit())
+	}
+
+	for _, hook := range opts.hooks {
+		hook(&out)
+	}
+
+	// data added by hooks is bounded by SetMaxDepth and cut at cycles like the rest
+	if len(opts.hooks) > 0 {
+		resolveLazy(out.Data, opts.depthLimit())
+	}
+
+	// hooks see redacted data, but may add fields of their own, such as a token taken from a
+	// context, so those are masked too
+	if len(opts.redactKeys) > 0 && len(opts.hooks) > 0 {
+		redactData(out.Data, opts.redactKeys, opts.depthLimit())
+	}
+
+	if opts.maxFieldBytes > 0 {
+		truncateData(out.Data, opts.maxFieldBytes, opts.depthLimit())
+	}
+
+	n, err := writeTo(w, &opts, out)
+
+	if err != nil {
+		opts.handleError(err)
+	} else if opts.metrics != nil {
+		opts.metrics.RecordWritten(level, n)
+	}
+
+	// a failing primary writer does not keep the record from the other routes
+	for _, route := range opts.levelWriters {
+		if level < route.min {
+			continue
+		}
+
+		if _, err := writeTo(route.w, &opts, out); err != nil {
+			opts.handleError(err)
+		}
+	}
+
+	for _, sink := range opts.sinks {
+		sinkOpts := opts
+		sinkOpts.formatter = sink.formatter
+		sinkOpts.indentPrefix, sinkOpts.indent = "", ""
+
+		if _, err := writeTo(sink.w, &sinkOpts, out); err != nil {
+			opts.handleError(err)
+		}
+	}
+
+	for _, callback := range opts.levelCallbacks {
+		if level >= callback.min {
+			callback.fn(out.copy())
+		}
+	}
 
 	if err != nil {
-		data = []byte("Logger unable to marshal log output to JSON: " + err.Error())
+		return
 	}
 
-	_, _ = l.Out.Write(data)
+	for _, e := range opts.emitters {
+		e.send(out.copy())
+	}
 }
 
+// writeTo encodes out for w, unless w implements RecordWriter, and writes it. Each record is
+// terminated by a single newline and handed to w in one Write, so byte writers produce
+// newline delimited output. The encoded bytes live in a pooled buffer, so writers must not
+// keep p after Write returns. It returns the number of bytes written, which is zero for a
+// RecordWriter.
+func writeTo(w io.Writer, opts *options, out WriteLog) (int, error) {
+	if rw, ok := w.(RecordWriter); ok {
+		return 0, rw.WriteRecord(out.copy())
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	formatter := opts.currentFormatter()
+	err := formatInto(buf, formatter, out)
+	data := buf.Bytes()
+
+	if err == nil {
+		data, err = capRecord(formatter, out, data, opts.maxRecordBytes)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("log: unable to encode record: %w", err)
+	}
+
+	if opts.indentPrefix != "" || opts.indent != "" {
+		indented := getBuffer()
+		defer putBuffer(indented)
+
+		if indentJSON(indented, data, opts) {
+			data = indented.Bytes()
+		}
+	}
+
+	return w.Write(append(data, '\n'))
+}
+
+// WriteLog is a single record. Its JSON field names are the one shape shared by everything
+// this package writes, including the access logs of the adapters, which go through the same
+// path as any other record: lowercase keys, data nested under "data", and the source as
+// "src":{"file":...,"line":...}, omitted when no source was captured.
+//
+// JSONFormatter and LogfmtFormatter write the core fields in the order of the struct below,
+// followed by the data keys sorted, so their output is byte-for-byte stable across runs.
+// ECSFormatter and DatadogFormatter write their keys sorted, StackdriverFormatter follows its
+// own schema and ConsoleWriter ends each line with the source.
 type WriteLog struct {
-	Time  time.Time `json:"time"`
-	App   string    `json:"app"`
-	Level string    `json:"level"`
-	Msg   string    `json:"msg"`
-	Data  Data      `json:"data,omitempty"`
-	Src   Src       `json:"Src"`
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	LevelNum *int      `json:"level_num,omitempty"` // see SetLevelNumbering
+	App      string    `json:"app"`
+	Host     string    `json:"host,omitempty"`
+	PID      int       `json:"pid,omitempty"`
+	Msg      string    `json:"msg"`
+	Src      Src       `json:"src"`
+	Data     Data      `json:"data,omitempty"`
+}
+
+// copy returns a WriteLog that does not share its data with w: Data and the maps and slices
+// nested in it are copied, so whoever receives the copy can change them without touching the
+// fields stored by With. Other values are shared. The data of a written record has been
+// walked already, so it holds no cycles.
+func (w WriteLog) copy() WriteLog {
+	w.Data = copyMap(w.Data)
+
+	if w.LevelNum != nil {
+		num := *w.LevelNum
+		w.LevelNum = &num
+	}
+
+	return w
+}
+
+// copyMap returns a copy of m that shares none of the maps and slices nested in it.
+func copyMap(m map[string]any) Data {
+	data := make(Data, len(m))
+
+	for key, value := range m {
+		data[key] = copyValue(value)
+	}
+
+	return data
+}
+
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case Data:
+		if v == nil {
+			return v
+		}
+
+		return copyMap(v)
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+
+		return map[string]any(copyMap(v))
+	case []any:
+		if v == nil {
+			return v
+		}
+
+		list := make([]any, len(v))
+
+		for i, item := range v {
+			list[i] = copyValue(item)
+		}
+
+		return list
+	}
+
+	return value
 }
 
+// Src is the location a record was written from. Package is only set when the logger was
+// asked to split it from File with SetSplitSource.
 type Src struct {
-	File string `json:"file"`
-	Line int    `json:"line"`
+	Package string `json:"package,omitempty"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+// Path returns the file including its package directory, as TruncateFile leaves it, whether
+// or not the two were split.
+//
+// Src{Package: "model", File: "user.go"} => "model/user.go"
+func (s Src) Path() string {
+	if s.Package == "" {
+		return s.File
+	}
+
+	return s.Package + "/" + s.File
+}
+
+// SplitPackage moves the directory of a truncated file into Package, leaving the filename in
+// File.
+//
+// "model/user.go" => Package "model", File "user.go"
+func (s *Src) SplitPackage() {
+	if i := strings.LastIndexAny(s.File, "/"+string(filepath.Separator)); i >= 0 {
+		s.Package = s.File[:i]
+		s.File = s.File[i+1:]
+	}
+}
+
+// IsZero reports whether no source was captured for the record.
+func (s Src) IsZero() bool {
+	return s.File == "" && s.Line == 0
 }
 
 // TruncateFile mutates the file string into either the filename and extension,
 // or the last directory (which is also usually the package name in Go) with the filename
-// and extension.
+// and extension. The version of a directory in the module cache is dropped, as is the major
+// version suffix of a module path there, so files at the root of a dependency are named after
+// its package rather than its version. Both slashes and backslashes separate directories.
 //
-// "project/Src/model/user.go" => "model/user.go"
+// "project/src/model/user.go" => "model/user.go"
 // "main.go" => "main.go"
+// "/go/pkg/mod/github.com/crit/log@v1.2.3/logger.go" => "log/logger.go"
+// "/go/pkg/mod/github.com/labstack/echo/v4@v4.15.4/echo.go" => "echo/echo.go"
+// "project/api/v1/users.go" => "v1/users.go"
 func (s *Src) TruncateFile() {
-	// "project/Src/model/user.go" => "project/Src/model", "user.go"
-	dir, file := filepath.Split(s.File)
+	// "project/src/model/user.go" => "project/src/model", "user.go"
+	i := strings.LastIndexAny(s.File, `/\`)
+	dir, file := s.File[:i+1], s.File[i+1:]
 
-	// "project/Src/model" => ["project", "Src", "model"]
+	// "project/src/model" => ["project", "src", "model"]
 	parts := strings.FieldsFunc(dir, func(r rune) bool {
-		return r == filepath.Separator
+		return r == '/' || r == '\\'
 	})
 
-	if len(parts) > 0 {
-		// => "model/user.go"
-		s.File = filepath.Join(parts[len(parts)-1], file)
-	} else {
+	if len(parts) == 0 {
 		s.File = file // "user.go"
+		return
 	}
+
+	// "log@v1.2.3" => "log"
+	last, _, cached := strings.Cut(parts[len(parts)-1], "@")
+
+	// "echo/v4@v4.15.4" => "echo", while a package directory such as "api/v1" is kept
+	if cached && isMajorVersion(last) && len(parts) > 1 {
+		last = parts[len(parts)-2]
+	}
+
+	// => "model/user.go"
+	s.File = filepath.Join(last, file)
+}
+
+// isMajorVersion reports whether elem is the major version suffix of a module path, such as
+// "v2".
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,536 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnEmitReceivesEachRecordOnce(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+
+	var mutex sync.Mutex
+	seen := map[string]int{}
+
+	l.OnEmit(func(rec WriteLog) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		seen[rec.Msg]++
+	})
+
+	child := l.With(Data{"user": 1})
+
+	for i := 0; i < 100; i++ {
+		l.Info("parent %d", i)
+		child.Info("child %d", i)
+	}
+
+	l.SetLevel(InfoLevel)
+	l.Debug("filtered out")
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(seen) != 200 {
+		t.Fatalf("observed %d distinct records, want 200", len(seen))
+	}
+
+	for msg, n := range seen {
+		if n != 1 {
+			t.Errorf("%q observed %d times, want 1", msg, n)
+		}
+	}
+}
+
+func TestOnEmitGetsACopy(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+
+	l.OnEmit(func(rec WriteLog) {
+		rec.Data["user"] = "changed"
+	})
+
+	l.OnEmit(func(rec WriteLog) {
+		if rec.Data["user"] != 1 {
+			t.Errorf("observer saw user %v, want 1", rec.Data["user"])
+		}
+	})
+
+	l.With(Data{"user": 1}).Info("saved")
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOnEmitDoesNotBlockLogging(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+	release := make(chan struct{})
+
+	l.OnEmit(func(WriteLog) {
+		<-release
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 10; i++ {
+			l.Info("record %d", i)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging waited for a slow observer")
+	}
+
+	close(release)
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWriteInternalConcurrentWithSetIncludeHost is meant for go test -race: records the logger
+// writes itself, such as sampling summaries, read the host while it may be changing.
+func TestWriteInternalConcurrentWithSetIncludeHost(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+
+	var wg sync.WaitGroup
+
+	start := make(chan struct{})
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		<-start
+
+		for i := 0; i < 1000; i++ {
+			l.SetIncludeHost(i%2 == 0)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		<-start
+
+		for i := 0; i < 1000; i++ {
+			l.writeInternal(WarningLevel, "sampled records", Data{"dropped": i})
+		}
+	}()
+
+	close(start)
+	wg.Wait()
+}
+
+// BenchmarkFilteredParallel logs records below the level from many goroutines at once. The
+// level is read atomically; locked is the same check made under the logger lock, as it was
+// before, for comparison.
+func BenchmarkFilteredParallel(b *testing.B) {
+	l := NewWithWriter("app", NoticeLevel, io.Discard)
+
+	b.Run("atomic", func(b *testing.B) {
+		b.ReportAllocs()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				l.Debug("filtered")
+			}
+		})
+	})
+
+	b.Run("locked", func(b *testing.B) {
+		b.ReportAllocs()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				l.mutex.Lock()
+				enabled := Level(l.level.Load()) <= DebugLevel
+				l.mutex.Unlock()
+
+				if enabled {
+					l.Debug("filtered")
+				}
+			}
+		})
+	})
+}
+
+func TestNopDoesNotAllocate(t *testing.T) {
+	l := NewNop()
+	data := Data{"user": 42}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Info("request %d done", 42)
+		l.Error("failed")
+		l.Log(ErrorLevel, data)
+	})
+
+	if allocs != 0 {
+		t.Errorf("a nop logger allocated %v times per run", allocs)
+	}
+}
+
+// BenchmarkNop logs through NewNop, which should cost no more than a filtered record.
+func BenchmarkNop(b *testing.B) {
+	l := NewNop()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		l.Error("request %s failed", "r-1")
+	}
+}
+
+// TestWithConcurrentInfo is meant for go test -race: deriving loggers from l while other
+// goroutines log through it and change its settings.
+func TestWithConcurrentInfo(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard).With(Data{"base": 1})
+
+	var wg sync.WaitGroup
+
+	start := make(chan struct{})
+
+	for g := 0; g < 4; g++ {
+		wg.Add(3)
+
+		go func(g int) {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 200; i++ {
+				l.With(Data{"worker": g, "i": i}).Info("derived")
+			}
+		}(g)
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 200; i++ {
+				l.Info("shared %d", i)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			for i := 0; i < 200; i++ {
+				l.SetOutput(io.Discard)
+				l.SetRedactKeys("token")
+				_ = l.Clone()
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+}
+
+func TestTruncateFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"project/src/model/user.go", "model/user.go"},
+		{"main.go", "main.go"},
+		{"/main.go", "main.go"},
+		{"", ""},
+		{"/go/pkg/mod/github.com/crit/log@v1.2.3/logger.go", "log/logger.go"},
+		{"/home/runner/go/pkg/mod/github.com/labstack/echo/v4@v4.15.4/echo.go", "echo/echo.go"},
+		{"/go/pkg/mod/github.com/labstack/echo/v4@v4.15.4/middleware/logger.go", "middleware/logger.go"},
+		{"/src/github.com/go-chi/chi/v5/mux.go", "v5/mux.go"},
+		{"project/api/v1/users.go", "v1/users.go"},
+		{"project/api/v2/users.go", "v2/users.go"},
+		{"/go/pkg/mod/gopkg.in/yaml.v3@v3.0.1/decode.go", "yaml.v3/decode.go"},
+		{"v2/main.go", "v2/main.go"},
+		{`C:\Users\dev\project\model\user.go`, "model/user.go"},
+		{`C:\Users\dev\go\pkg\mod\github.com\labstack\echo\v4@v4.15.4\echo.go`, "echo/echo.go"},
+	}
+
+	for _, tt := range tests {
+		src := Src{File: tt.file, Line: 1}
+		src.TruncateFile()
+
+		if want := filepath.FromSlash(tt.want); src.File != want {
+			t.Errorf("TruncateFile(%q) = %q, want %q", tt.file, src.File, want)
+		}
+	}
+}
+
+func TestObserversGetDeepCopies(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetIncludeSource(false)
+
+	mutate := func(rec WriteLog) {
+		rec.Data["user"].(Data)["id"] = "mutated"
+		rec.Data["tags"].([]any)[0] = "mutated"
+		rec.Data["meta"].(map[string]any)["region"] = "mutated"
+	}
+
+	l.OnEmit(mutate)
+	l.OnLevel(InfoLevel, mutate)
+
+	child := l.With(Data{
+		"user": Data{"id": "u-1"},
+		"tags": []any{"a"},
+		"meta": map[string]any{"region": "eu"},
+	})
+
+	child.Info("first")
+
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	child.Info("second")
+
+	want := `"data":{"meta":{"region":"eu"},"tags":["a"],"user":{"id":"u-1"}}`
+
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("an observer changed the logger's data: %s", buf.Bytes())
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	l := NewWithWriter("app", WarningLevel, io.Discard)
+
+	for _, level := range []Level{DebugLevel, InfoLevel, NoticeLevel, WarningLevel, ErrorLevel, EmergencyLevel} {
+		if got, want := l.Enabled(level), level >= WarningLevel; got != want {
+			t.Errorf("Enabled(%s) = %v, want %v", level, got, want)
+		}
+	}
+
+	l.SetLevel(DebugLevel)
+
+	if !l.Enabled(DebugLevel) {
+		t.Error("Enabled(debug) = false after SetLevel(debug)")
+	}
+
+	if NewNop().Enabled(EmergencyLevel) {
+		t.Error("a nop logger reports emergency as enabled")
+	}
+}
+
+func TestPushLevel(t *testing.T) {
+	l := NewWithWriter("app", WarningLevel, io.Discard)
+
+	restoreDebug := l.PushLevel(DebugLevel)
+
+	if !l.Enabled(DebugLevel) || l.Level() != DebugLevel {
+		t.Errorf("after PushLevel(debug), level = %s", l.Level())
+	}
+
+	restoreError := l.PushLevel(ErrorLevel)
+
+	if l.Enabled(WarningLevel) {
+		t.Error("warning enabled after PushLevel(error)")
+	}
+
+	restoreError()
+
+	if l.Level() != DebugLevel {
+		t.Errorf("after the inner restore, level = %s, want debug", l.Level())
+	}
+
+	restoreDebug()
+
+	if l.Level() != WarningLevel || l.Enabled(InfoLevel) {
+		t.Errorf("after the outer restore, level = %s, want warning", l.Level())
+	}
+
+	l.SetLevel(InfoLevel)
+	restoreError()
+	restoreDebug()
+
+	if l.Level() != InfoLevel {
+		t.Errorf("restoring twice changed the level to %s", l.Level())
+	}
+}
+
+// appName returns the app name of the record l writes.
+func appName(t *testing.T, l *Logger) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	l.SetOutput(&buf)
+	l.Info("record")
+
+	records := decodeRecords(t, &buf)
+
+	if len(records) != 1 {
+		t.Fatalf("wrote %d records, want 1", len(records))
+	}
+
+	return records[0].App
+}
+
+func TestNamed(t *testing.T) {
+	l := NewWithWriter("api", DebugLevel, io.Discard)
+
+	tests := []struct {
+		l    *Logger
+		want string
+	}{
+		{l.Named("users"), "api.users"},
+		{l.Named("users").Named("store").Named("pg"), "api.users.store.pg"},
+		{NewWithWriter("", DebugLevel, io.Discard).Named("worker").Named("queue"), "worker.queue"},
+		{l, "api"},
+	}
+
+	for _, tt := range tests {
+		if got := appName(t, tt.l); got != tt.want {
+			t.Errorf("app = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestWithApp(t *testing.T) {
+	l := NewWithWriter("api", DebugLevel, io.Discard).With(Data{"user": 1})
+	child := l.Named("users").WithApp("billing")
+
+	if got := appName(t, child); got != "billing" {
+		t.Errorf("app = %q, want billing", got)
+	}
+
+	if got := appName(t, child.Named("invoices")); got != "billing.invoices" {
+		t.Errorf("app = %q, want billing.invoices", got)
+	}
+
+	if got := appName(t, l); got != "api" {
+		t.Errorf("parent app = %q, want api", got)
+	}
+
+	if got := recordData(t, child)["user"]; got != 1.0 {
+		t.Errorf("user = %v, want the parent's data kept", got)
+	}
+}
+
+func TestLogAt(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetLevel(InfoLevel)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	l.LogAt(at, WarningLevel, "backfilled %d", 1)
+	l.LogAt(time.Time{}, InfoLevel, "now")
+	l.LogAt(at, DebugLevel, "filtered")
+
+	records := decodeRecords(t, &buf)
+
+	if len(records) != 2 {
+		t.Fatalf("wrote %v, want two records", messages(records))
+	}
+
+	if rec := records[0]; !rec.Time.Equal(at) || rec.Level != "warning" || rec.Msg != "backfilled 1" {
+		t.Errorf("first record = %s %s %q, want %s warning", rec.Time, rec.Level, rec.Msg, at)
+	}
+
+	if !records[1].Time.Equal(goldenTime) {
+		t.Errorf("zero time stamped %s, want the clock's %s", records[1].Time, goldenTime)
+	}
+}
+
+func TestSetSourceLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetSourceLevel(WarningLevel)
+
+	l.Info("no source")
+	l.Warn("with source")
+	l.Error("with source")
+
+	l.SetIncludeSource(false)
+	l.Error("source off")
+
+	l.SetIncludeSource(true)
+	l.SetSourceLevel(DebugLevel)
+	l.Debug("with source")
+
+	for _, rec := range decodeRecords(t, &buf) {
+		want := rec.Msg == "with source"
+
+		if got := rec.Src.File != ""; got != want {
+			t.Errorf("%s %q: src = %+v, want captured %v", rec.Level, rec.Msg, rec.Src, want)
+		}
+	}
+}
+
+func TestSetSplitSource(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	l.SetSplitSource(true)
+	l.Info("split")
+
+	l.SetSplitSource(false)
+	l.Info("joined")
+
+	records := decodeRecords(t, &buf)
+
+	if len(records) != 2 {
+		t.Fatalf("wrote %d records, want 2", len(records))
+	}
+
+	split, joined := records[0].Src, records[1].Src
+
+	if split.Package == "" || split.File != "logger_test.go" || split.Line == 0 {
+		t.Errorf("split src = %+v, want the package apart from logger_test.go", split)
+	}
+
+	if joined.Package != "" || joined.File != split.Package+"/logger_test.go" {
+		t.Errorf("joined src = %+v, want %s/logger_test.go", joined, split.Package)
+	}
+}
+
+func TestOnLevel(t *testing.T) {
+	var alerts, errs []string
+
+	l := NewWithWriter("app", DebugLevel, failingWriter{err: io.ErrClosedPipe})
+	l.SetErrorHandler(func(error) {})
+	l.OnLevel(AlertLevel, func(rec WriteLog) { alerts = append(alerts, rec.Msg) })
+	l.OnLevel(ErrorLevel, func(rec WriteLog) {
+		errs = append(errs, rec.Msg)
+		rec.Data["changed"] = true
+	})
+
+	child := l.With(Data{"user": 1})
+
+	child.Warn("warning")
+	child.Error("error")
+	l.Alert("alert")
+	child.Emergency("emergency")
+
+	if want := []string{"alert", "emergency"}; !reflect.DeepEqual(alerts, want) {
+		t.Errorf("alert callback got %q, want %q", alerts, want)
+	}
+
+	if want := []string{"error", "alert", "emergency"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("error callback got %q, want %q", errs, want)
+	}
+
+	if got := recordData(t, child); got["changed"] != nil {
+		t.Errorf("a callback changed the logger's data: %v", got)
+	}
+}
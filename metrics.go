@@ -0,0 +1,66 @@
+package log
+
+import "sync"
+
+// Metrics receives a call for every record a logger writes successfully to its primary writer,
+// with the record's level and encoded size. The size is zero for writers implementing
+// RecordWriter, which receive records unencoded. Implementations must be safe for concurrent
+// use and should return quickly; they run on the logging goroutine.
+type Metrics interface {
+	RecordWritten(level Level, bytes int)
+}
+
+// SetMetrics reports written records to m. Passing nil stops reporting. Loggers derived from
+// l afterwards report to the same m.
+func (l *Logger) SetMetrics(m Metrics) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.metrics = m
+}
+
+// LevelStats counts the records written at one level.
+type LevelStats struct {
+	Records int64 `json:"records"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// Counters is an in-memory Metrics that keeps per level totals:
+//
+//	counters := &log.Counters{}
+//	logger.SetMetrics(counters)
+//	...
+//	stats := counters.Stats()
+//	fmt.Println(stats[log.ErrorLevel].Records)
+type Counters struct {
+	mutex  sync.Mutex
+	levels map[Level]LevelStats
+}
+
+// RecordWritten implements Metrics.
+func (c *Counters) RecordWritten(level Level, bytes int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.levels == nil {
+		c.levels = make(map[Level]LevelStats)
+	}
+
+	stats := c.levels[level]
+	stats.Records++
+	stats.Bytes += int64(bytes)
+	c.levels[level] = stats
+}
+
+// Stats returns a snapshot of the totals for every level written so far.
+func (c *Counters) Stats() map[Level]LevelStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := make(map[Level]LevelStats, len(c.levels))
+
+	for level, s := range c.levels {
+		stats[level] = s
+	}
+
+	return stats
+}
@@ -0,0 +1,584 @@
+// Package otlp exports records to an OpenTelemetry collector as OTLP log records, using the
+// OTLP/HTTP JSON encoding so it needs nothing beyond the standard library:
+//
+//	exporter := otlp.NewExporter("http://collector:4318/v1/logs")
+//	logger.SetOutput(exporter)
+//	defer logger.Close()
+//
+// Each record becomes a LogRecord: its time, its severity on the OpenTelemetry scale, the
+// message as body and its data as attributes. The app, host and pid go into the resource.
+// Records carrying trace_id and span_id, as added by the oteltrace.OTelTrace context
+// extractor, are correlated with their trace.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crit/log"
+)
+
+// Defaults for the Exporter batching.
+const (
+	defaultBatchSize  = 512
+	defaultInterval   = time.Second
+	defaultMaxPending = 16 * defaultBatchSize
+)
+
+// scopeName identifies this package as the instrumentation scope of exported records.
+const scopeName = "github.com/crit/log"
+
+// Exporter is a log writer that batches records and posts them to an OTLP/HTTP logs
+// endpoint. A batch is sent when it is full and at least once per interval, in the
+// background, so a slow collector never blocks logging. At most SetMaxPending records wait
+// to be sent; while a collector is down or slow the oldest are dropped and counted to make
+// room for new ones. Failures are reported through the standard library logger unless an
+// error handler is set. Flush sends what is pending and Close does the same before stopping
+// the exporter.
+type Exporter struct {
+	url        string
+	mutex      sync.Mutex
+	send       sync.Mutex // held while flushing
+	client     *http.Client
+	headers    http.Header
+	onError    func(error)
+	batchSize  int
+	interval   time.Duration
+	maxPending int
+	closed     bool
+	pending    []log.WriteLog
+	dropped    atomic.Int64
+	start      sync.Once
+	kick       chan struct{}
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewExporter returns an exporter that posts records to url, the full logs endpoint of a
+// collector, usually ending in /v1/logs.
+func NewExporter(url string) *Exporter {
+	return &Exporter{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		headers:    http.Header{},
+		batchSize:  defaultBatchSize,
+		interval:   defaultInterval,
+		maxPending: defaultMaxPending,
+		kick:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetClient replaces the HTTP client requests are made with. A nil client is ignored.
+func (e *Exporter) SetClient(client *http.Client) {
+	if client == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.client = client
+}
+
+// SetHeader adds a header sent with every request, such as an API key required by a hosted
+// collector.
+func (e *Exporter) SetHeader(key, value string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.headers.Set(key, value)
+}
+
+// SetBatch sets how many records are sent in one request and how long a record may wait for
+// the batch to fill. It must be called before the first record is written. Non-positive
+// values keep the defaults of 512 records and one second.
+func (e *Exporter) SetBatch(size int, interval time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if size > 0 {
+		e.batchSize = size
+	}
+
+	if interval > 0 {
+		e.interval = interval
+	}
+}
+
+// SetMaxPending caps the number of records waiting to be sent. When the cap is reached the
+// oldest waiting record is dropped for each new one. A non-positive n keeps the default of
+// 8192.
+func (e *Exporter) SetMaxPending(n int) {
+	if n <= 0 {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.maxPending = n
+}
+
+// SetErrorHandler routes delivery failures to fn instead of the standard library logger. fn
+// is called from the exporter goroutine.
+func (e *Exporter) SetErrorHandler(fn func(error)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.onError = fn
+}
+
+// WriteRecord implements log.RecordWriter, queueing rec for the next batch.
+func (e *Exporter) WriteRecord(rec log.WriteLog) error {
+	e.start.Do(func() { go e.run() })
+
+	e.mutex.Lock()
+
+	if e.closed {
+		e.mutex.Unlock()
+		return os.ErrClosed
+	}
+
+	if over := len(e.pending) - e.maxPending + 1; over > 0 {
+		e.pending = e.pending[over:]
+		e.dropped.Add(int64(over))
+	}
+
+	e.pending = append(e.pending, rec)
+	full := len(e.pending) >= e.batchSize
+
+	e.mutex.Unlock()
+
+	if full {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Write implements io.Writer for records that reach the exporter already encoded, such as
+// through a MultiWriter. p must hold one JSON record as the logger writes it.
+func (e *Exporter) Write(p []byte) (int, error) {
+	var rec log.WriteLog
+
+	if err := json.Unmarshal(p, &rec); err != nil {
+		return 0, fmt.Errorf("otlp: decode record: %w", err)
+	}
+
+	if err := e.WriteRecord(rec); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Pending returns the number of records waiting to be sent. Records in a request under way
+// are not counted.
+func (e *Exporter) Pending() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.pending)
+}
+
+// Dropped returns the number of records discarded because SetMaxPending records were already
+// waiting.
+func (e *Exporter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Flush sends every pending record and returns the first delivery error.
+func (e *Exporter) Flush() error {
+	var first error
+
+	// one flush at a time, so batches reach the collector in the order they were written
+	e.send.Lock()
+	defer e.send.Unlock()
+
+	for {
+		e.mutex.Lock()
+		batch := e.take()
+		e.mutex.Unlock()
+
+		if len(batch) == 0 {
+			return first
+		}
+
+		if err := e.export(context.Background(), batch); err != nil && first == nil {
+			first = err
+		}
+	}
+}
+
+// Close sends every pending record and stops the exporter. Records written afterwards are
+// rejected with os.ErrClosed.
+func (e *Exporter) Close() error {
+	e.mutex.Lock()
+
+	if e.closed {
+		e.mutex.Unlock()
+		return nil
+	}
+
+	e.closed = true
+	e.mutex.Unlock()
+
+	// make sure run has started so done is closed
+	e.start.Do(func() { go e.run() })
+	close(e.stop)
+	<-e.done
+
+	return e.Flush()
+}
+
+// take removes up to one batch from pending. The caller holds the mutex.
+func (e *Exporter) take() []log.WriteLog {
+	n := len(e.pending)
+
+	if n > e.batchSize {
+		n = e.batchSize
+	}
+
+	batch := e.pending[:n:n]
+	e.pending = e.pending[n:]
+
+	return batch
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	e.mutex.Lock()
+	interval := e.interval
+	e.mutex.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		case <-e.kick:
+		}
+
+		if err := e.Flush(); err != nil {
+			e.fail(err)
+		}
+	}
+}
+
+func (e *Exporter) fail(err error) {
+	e.mutex.Lock()
+	fn := e.onError
+	e.mutex.Unlock()
+
+	if fn != nil {
+		fn(err)
+		return
+	}
+
+	stdlog.Printf("internal/logger otlp exporter error: %s", err)
+}
+
+// export posts batch as one request.
+func (e *Exporter) export(ctx context.Context, batch []log.WriteLog) error {
+	body, err := json.Marshal(encode(batch))
+
+	if err != nil {
+		return fmt.Errorf("otlp: encode records: %w", err)
+	}
+
+	e.mutex.Lock()
+	client := e.client
+	headers := e.headers.Clone()
+	e.mutex.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header = headers
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("otlp: collector returned %s", res.Status)
+	}
+
+	return nil
+}
+
+// The types below mirror the parts of the OTLP/JSON logs data model the exporter writes.
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *kvlist     `json:"kvlistValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type kvlist struct {
+	Values []keyValue `json:"values"`
+}
+
+// resourceKey groups records that share a resource.
+type resourceKey struct {
+	app  string
+	host string
+	pid  int
+}
+
+// encode builds the request for batch, with one resource per distinct app, host and pid in
+// the order they first appear.
+func encode(batch []log.WriteLog) exportRequest {
+	var req exportRequest
+
+	index := map[resourceKey]int{}
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for _, rec := range batch {
+		key := resourceKey{app: rec.App, host: rec.Host, pid: rec.PID}
+		i, ok := index[key]
+
+		if !ok {
+			i = len(req.ResourceLogs)
+			index[key] = i
+			req.ResourceLogs = append(req.ResourceLogs, resourceLogs{
+				Resource:  resource{Attributes: resourceAttributes(key)},
+				ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}}},
+			})
+		}
+
+		logs := &req.ResourceLogs[i].ScopeLogs[0]
+		logs.LogRecords = append(logs.LogRecords, record(rec, observed))
+	}
+
+	return req
+}
+
+func resourceAttributes(key resourceKey) []keyValue {
+	attrs := []keyValue{{Key: "service.name", Value: stringValue(key.app)}}
+
+	if key.host != "" {
+		attrs = append(attrs, keyValue{Key: "host.name", Value: stringValue(key.host)})
+	}
+
+	if key.pid != 0 {
+		attrs = append(attrs, keyValue{Key: "process.pid", Value: intValue(int64(key.pid))})
+	}
+
+	return attrs
+}
+
+// record maps rec onto an OTLP log record. trace_id and span_id in its data become the
+// record's trace context instead of attributes; the source becomes code.filepath and
+// code.lineno.
+func record(rec log.WriteLog, observed string) logRecord {
+	severity, _ := log.OTelLevelNumber.Number(log.ToLevel(rec.Level))
+
+	out := logRecord{
+		TimeUnixNano:         strconv.FormatInt(rec.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: observed,
+		SeverityNumber:       severity,
+		SeverityText:         rec.Level,
+		Body:                 stringValue(rec.Msg),
+	}
+
+	keys := make([]string, 0, len(rec.Data))
+
+	for key := range rec.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := rec.Data[key]
+
+		switch key {
+		case "trace_id":
+			if id, ok := value.(string); ok && len(id) == 32 {
+				out.TraceID = id
+				continue
+			}
+		case "span_id":
+			if id, ok := value.(string); ok && len(id) == 16 {
+				out.SpanID = id
+				continue
+			}
+		}
+
+		out.Attributes = append(out.Attributes, keyValue{Key: key, Value: attributeValue(value, 0)})
+	}
+
+	if !rec.Src.IsZero() {
+		out.Attributes = append(out.Attributes,
+			keyValue{Key: "code.filepath", Value: stringValue(rec.Src.Path())},
+			keyValue{Key: "code.lineno", Value: intValue(int64(rec.Src.Line))},
+		)
+	}
+
+	return out
+}
+
+// maxAttributeDepth bounds how deep nested data is mapped before it is written as JSON text.
+const maxAttributeDepth = 8
+
+// attributeValue maps a data value onto an OTLP value. Maps and slices become key-value lists
+// and arrays; anything else without a direct counterpart is written as its JSON encoding.
+func attributeValue(value any, depth int) anyValue {
+	switch v := value.(type) {
+	case nil:
+		return anyValue{}
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case error:
+		return stringValue(v.Error())
+	case fmt.Stringer:
+		return stringValue(v.String())
+	}
+
+	if depth < maxAttributeDepth {
+		switch v := value.(type) {
+		case log.Data:
+			return mapValue(v, depth)
+		case map[string]any:
+			return mapValue(v, depth)
+		case []any:
+			list := make([]anyValue, len(v))
+
+			for i, item := range v {
+				list[i] = attributeValue(item, depth+1)
+			}
+
+			return anyValue{ArrayValue: &arrayValue{Values: list}}
+		}
+	}
+
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return stringValue(fmt.Sprint(value))
+	}
+
+	return stringValue(string(data))
+}
+
+func mapValue(m map[string]any, depth int) anyValue {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	values := make([]keyValue, len(keys))
+
+	for i, key := range keys {
+		values[i] = keyValue{Key: key, Value: attributeValue(m[key], depth+1)}
+	}
+
+	return anyValue{KvlistValue: &kvlist{Values: values}}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(n int64) anyValue {
+	s := strconv.FormatInt(n, 10)
+	return anyValue{IntValue: &s}
+}
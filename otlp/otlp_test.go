@@ -0,0 +1,336 @@
+package otlp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crit/log"
+)
+
+// collector is a mock OTLP/HTTP logs endpoint recording every request it receives.
+type collector struct {
+	mutex    sync.Mutex
+	status   int
+	requests []exportRequest
+	headers  []http.Header
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req exportRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("collector: decode request: %v", err)
+		}
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		c.requests = append(c.requests, req)
+		c.headers = append(c.headers, r.Header.Clone())
+		w.WriteHeader(c.status)
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return c, srv
+}
+
+// received returns the requests received so far and their headers.
+func (c *collector) received() ([]exportRequest, []http.Header) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.requests, c.headers
+}
+
+// records returns every log record received, in order.
+func (c *collector) records() []logRecord {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var out []logRecord
+
+	for _, req := range c.requests {
+		for _, res := range req.ResourceLogs {
+			for _, scope := range res.ScopeLogs {
+				out = append(out, scope.LogRecords...)
+			}
+		}
+	}
+
+	return out
+}
+
+func attribute(attrs []keyValue, key string) (anyValue, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return anyValue{}, false
+}
+
+func TestExporterSendsRecords(t *testing.T) {
+	c, srv := newCollector(t)
+
+	exporter := NewExporter(srv.URL + "/v1/logs")
+	exporter.SetHeader("Authorization", "Bearer key")
+	exporter.SetBatch(100, time.Hour)
+
+	logger := log.NewWithWriter("api", log.DebugLevel, exporter)
+
+	logger.With(log.Data{
+		"user":     42,
+		"cached":   true,
+		"auth":     log.Data{"method": "token"},
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}).Warn("slow request")
+
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, headers := c.received()
+
+	if len(requests) != 1 {
+		t.Fatalf("collector got %d requests, want 1", len(requests))
+	}
+
+	if got := headers[0].Get("Authorization"); got != "Bearer key" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	if got := headers[0].Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	res := requests[0].ResourceLogs[0]
+
+	if name, _ := attribute(res.Resource.Attributes, "service.name"); name.StringValue == nil || *name.StringValue != "api" {
+		t.Errorf("resource attributes = %+v", res.Resource.Attributes)
+	}
+
+	if res.ScopeLogs[0].Scope.Name != scopeName {
+		t.Errorf("scope = %q", res.ScopeLogs[0].Scope.Name)
+	}
+
+	rec := res.ScopeLogs[0].LogRecords[0]
+	severity, _ := log.OTelLevelNumber.Number(log.WarningLevel)
+
+	if rec.SeverityNumber != severity || rec.SeverityText != "warning" {
+		t.Errorf("severity = %d %q", rec.SeverityNumber, rec.SeverityText)
+	}
+
+	if rec.Body.StringValue == nil || *rec.Body.StringValue != "slow request" {
+		t.Errorf("body = %+v", rec.Body)
+	}
+
+	if rec.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || rec.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("trace context = %q %q", rec.TraceID, rec.SpanID)
+	}
+
+	if _, ok := attribute(rec.Attributes, "trace_id"); ok {
+		t.Error("trace_id was also written as an attribute")
+	}
+
+	if user, _ := attribute(rec.Attributes, "user"); user.IntValue == nil || *user.IntValue != "42" {
+		t.Errorf("user = %+v", user)
+	}
+
+	if cached, _ := attribute(rec.Attributes, "cached"); cached.BoolValue == nil || !*cached.BoolValue {
+		t.Errorf("cached = %+v", cached)
+	}
+
+	auth, _ := attribute(rec.Attributes, "auth")
+
+	if auth.KvlistValue == nil {
+		t.Fatalf("auth = %+v", auth)
+	}
+
+	if method, _ := attribute(auth.KvlistValue.Values, "method"); method.StringValue == nil || *method.StringValue != "token" {
+		t.Errorf("auth.method = %+v", method)
+	}
+
+	if _, ok := attribute(rec.Attributes, "code.filepath"); !ok {
+		t.Error("source was not written as code.filepath")
+	}
+}
+
+func TestExporterBatches(t *testing.T) {
+	c, srv := newCollector(t)
+
+	exporter := NewExporter(srv.URL)
+	exporter.SetBatch(2, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		if err := exporter.WriteRecord(log.WriteLog{Level: "info", App: "api", Msg: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := c.records()
+
+	if len(records) != 5 {
+		t.Fatalf("collector got %d records, want 5", len(records))
+	}
+
+	for i, rec := range records {
+		if *rec.Body.StringValue != strconv.Itoa(i) {
+			t.Errorf("record %d has body %q", i, *rec.Body.StringValue)
+		}
+	}
+
+	requests, _ := c.received()
+
+	for i, req := range requests {
+		if n := len(req.ResourceLogs[0].ScopeLogs[0].LogRecords); n > 2 {
+			t.Errorf("request %d carried %d records, over the batch size", i, n)
+		}
+	}
+
+	if exporter.Pending() != 0 {
+		t.Errorf("%d records still pending after Close", exporter.Pending())
+	}
+}
+
+func TestExporterSendsOnInterval(t *testing.T) {
+	c, srv := newCollector(t)
+
+	exporter := NewExporter(srv.URL)
+	exporter.SetBatch(100, 10*time.Millisecond)
+	defer exporter.Close()
+
+	if err := exporter.WriteRecord(log.WriteLog{Level: "info", App: "api", Msg: "tick"}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for len(c.records()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("record was not sent within the interval")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExporterReportsCollectorErrors(t *testing.T) {
+	c, srv := newCollector(t)
+	c.mutex.Lock()
+	c.status = http.StatusServiceUnavailable
+	c.mutex.Unlock()
+
+	exporter := NewExporter(srv.URL)
+	exporter.SetBatch(100, time.Hour)
+
+	if err := exporter.WriteRecord(log.WriteLog{Level: "error", App: "api", Msg: "lost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exporter.Flush(); err == nil {
+		t.Error("Flush returned no error for a failing collector")
+	}
+}
+
+func TestExporterRejectsRecordsAfterClose(t *testing.T) {
+	_, srv := newCollector(t)
+
+	exporter := NewExporter(srv.URL)
+
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exporter.WriteRecord(log.WriteLog{Msg: "late"}); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("WriteRecord after Close = %v, want os.ErrClosed", err)
+	}
+}
+
+func TestExporterDropsOldestRecordsOverMaxPending(t *testing.T) {
+	c, srv := newCollector(t)
+
+	exporter := NewExporter(srv.URL)
+	exporter.SetBatch(100, time.Hour)
+	exporter.SetMaxPending(3)
+
+	for i := 0; i < 5; i++ {
+		if err := exporter.WriteRecord(log.WriteLog{Level: "info", App: "api", Msg: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exporter.Pending() != 3 || exporter.Dropped() != 2 {
+		t.Errorf("pending %d, dropped %d, want 3 and 2", exporter.Pending(), exporter.Dropped())
+	}
+
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+
+	for _, rec := range c.records() {
+		bodies = append(bodies, *rec.Body.StringValue)
+	}
+
+	if len(bodies) != 3 || bodies[0] != "2" || bodies[2] != "4" {
+		t.Errorf("collector got %q, want the newest three records", bodies)
+	}
+}
+
+func TestExporterBoundedWhileCollectorIsDown(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	var mutex sync.Mutex
+	var failures int
+
+	exporter := NewExporter(srv.URL)
+	exporter.SetBatch(10, time.Millisecond)
+	exporter.SetMaxPending(20)
+	exporter.SetErrorHandler(func(error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failures++
+	})
+
+	for i := 0; i < 1000; i++ {
+		if err := exporter.WriteRecord(log.WriteLog{Level: "info", App: "api", Msg: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := exporter.Pending(); n > 20 {
+			t.Fatalf("%d records pending, over the cap of 20", n)
+		}
+	}
+
+	// Close fails to deliver whatever is still pending, which is all it can do
+	_ = exporter.Close()
+
+	if exporter.Pending() != 0 {
+		t.Errorf("%d records still pending after Close", exporter.Pending())
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if failures == 0 && exporter.Dropped() == 0 {
+		t.Error("a down collector caused neither failures nor drops")
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// SetRateLimit bounds the number of records written per second using a token bucket that
+// allows bursts of up to perSecond records. Records over the limit are dropped and counted; the
+// count is reported in a "rate limited records" record at most once per second once records
+// flow again. The limiter is shared by loggers derived from this one so that together they
+// stay under the limit. Tokens are refilled by the clock set by SetClock. Zero disables rate
+// limiting. Records exempted with Always, and Alert and Emergency records, are never limited.
+func (l *Logger) SetRateLimit(perSecond int) {
+	var r *rateLimiter
+
+	if perSecond > 0 {
+		r = &rateLimiter{
+			rate:   float64(perSecond),
+			tokens: float64(perSecond),
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.rateLimiter = r
+}
+
+type rateLimiter struct {
+	mutex    sync.Mutex
+	rate     float64
+	tokens   float64
+	last     time.Time
+	dropped  int
+	reported time.Time
+}
+
+// allow takes a token if one is available. When records were dropped earlier and at least a
+// second has passed since the last report, it also returns the number dropped.
+func (r *rateLimiter) allow(now time.Time) (ok bool, dropped int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// the bucket starts full at the first record, whatever clock it is read from
+	if r.last.IsZero() {
+		r.last = now
+	}
+
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	r.last = now
+
+	if r.tokens > r.rate {
+		r.tokens = r.rate
+	}
+
+	if r.tokens < 1 {
+		r.dropped++
+		return false, 0
+	}
+
+	r.tokens--
+
+	if r.dropped > 0 && now.Sub(r.reported) >= time.Second {
+		dropped = r.dropped
+		r.dropped = 0
+		r.reported = now
+	}
+
+	return true, dropped
+}
+
+// withinRate consults the rate limiter, if any, and writes the dropped record report.
+func (l *Logger) withinRate() bool {
+	l.mutex.Lock()
+	r := l.opts.rateLimiter
+	clock := l.opts.clock
+	l.mutex.Unlock()
+
+	if r == nil {
+		return true
+	}
+
+	ok, dropped := r.allow(now(clock))
+
+	if dropped > 0 {
+		l.writeInternal(WarningLevel, "rate limited records", Data{"dropped": dropped})
+	}
+
+	return ok
+}
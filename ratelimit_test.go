@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPerSecond(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	l := goldenLogger(&buf)
+	l.SetClock(clock.Now)
+	l.SetRateLimit(3)
+
+	for i := 0; i < 10; i++ {
+		l.Info("burst")
+	}
+
+	if got := decodeRecords(t, &buf); len(got) != 3 {
+		t.Fatalf("a burst wrote %d records, want the 3 allowed per second", len(got))
+	}
+
+	// 400ms refills one token; the first record to get through reports the drops before it
+	clock.Advance(400 * time.Millisecond)
+	l.Info("refilled")
+	l.Info("dropped")
+
+	got := decodeRecords(t, &buf)
+
+	if len(got) != 2 || got[0].Msg != "rate limited records" || got[1].Msg != "refilled" {
+		t.Fatalf("after 400ms wrote %v, want the drop report and one record", messages(got))
+	}
+
+	if got[0].Data["dropped"] != float64(7) {
+		t.Errorf("report counted %v dropped records, want 7", got[0].Data["dropped"])
+	}
+
+	// reports come at most once a second
+	clock.Advance(400 * time.Millisecond)
+	l.Info("unreported")
+
+	if got := decodeRecords(t, &buf); len(got) != 1 || got[0].Msg != "unreported" {
+		t.Fatalf("wrote %v, want the record without a second report", messages(got))
+	}
+
+	clock.Advance(time.Second)
+	l.Info("again")
+
+	got = decodeRecords(t, &buf)
+
+	if len(got) != 2 || got[0].Data["dropped"] != float64(1) || got[1].Msg != "again" {
+		t.Errorf("after a second wrote %+v, want a report of 1 dropped record and the record", got)
+	}
+}
+
+func TestRateLimitSharedWithChildren(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetClock(newFakeClock().Now)
+	l.SetRateLimit(2)
+
+	child := l.With(Data{"user": 1})
+
+	l.Info("parent")
+	child.Info("child")
+	child.Info("over")
+	l.Info("over")
+
+	if got := decodeRecords(t, &buf); len(got) != 2 {
+		t.Errorf("parent and child wrote %v together, want 2", messages(got))
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import "strings"
+
+// SetRedactKeys masks the value of any data field whose key matches one of keys, ignoring
+// case, with "[REDACTED]" before the record is encoded. Nested maps and slices are searched as
+// well, down to the depth set by SetMaxDepth, so a password is masked wherever it ends up.
+// Calling it again replaces the list; calling it with no keys disables redaction.
+func (l *Logger) SetRedactKeys(keys ...string) {
+	var set map[string]bool
+
+	if len(keys) > 0 {
+		set = make(map[string]bool, len(keys))
+
+		for _, key := range keys {
+			set[strings.ToLower(key)] = true
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.redactKeys = set
+}
+
+// redactData masks matching keys in data, which must be owned by the caller. Nested maps are
+// copied before masking so values shared with the application are never modified.
+func redactData(data Data, keys map[string]bool, limit int) {
+	for key, value := range data {
+		if keys[strings.ToLower(key)] {
+			data[key] = redactedValue
+			continue
+		}
+
+		data[key] = redactValue(value, keys, 1, limit)
+	}
+}
+
+func redactValue(value any, keys map[string]bool, depth, limit int) any {
+	if depth > limit {
+		return value
+	}
+
+	switch v := value.(type) {
+	case Data:
+		return Data(redactMap(v, keys, depth, limit))
+	case map[string]any:
+		return redactMap(v, keys, depth, limit)
+	case []any:
+		list := make([]any, len(v))
+
+		for i, item := range v {
+			list[i] = redactValue(item, keys, depth+1, limit)
+		}
+
+		return list
+	}
+
+	return value
+}
+
+func redactMap(m map[string]any, keys map[string]bool, depth, limit int) map[string]any {
+	set := make(map[string]any, len(m))
+
+	for key, value := range m {
+		if keys[strings.ToLower(key)] {
+			set[key] = redactedValue
+			continue
+		}
+
+		set[key] = redactValue(value, keys, depth+1, limit)
+	}
+
+	return set
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedactKeys(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetRedactKeys("password", "Token")
+
+	user := Data{"name": "ada", "password": "hunter2"}
+
+	l.With(Data{
+		"user":     user,
+		"TOKEN":    "abc",
+		"sessions": []any{Data{"token": "def"}},
+	}).Info("login")
+
+	out := buf.String()
+
+	for _, secret := range []string{"hunter2", "abc", "def"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q was not redacted: %s", secret, out)
+		}
+	}
+
+	if user["password"] != "hunter2" {
+		t.Error("redaction modified the caller's map")
+	}
+}
+
+func TestRedactKeysCoversHookFields(t *testing.T) {
+	var buf bytes.Buffer
+	var seen any
+
+	l := goldenLogger(&buf)
+	l.SetRedactKeys("token")
+
+	l.AddHook(func(rec *WriteLog) {
+		seen = rec.Data["token"]
+		rec.Data["auth"] = Data{"token": "from-context"}
+	})
+
+	l.With(Data{"token": "abc"}).Info("request")
+
+	if seen != redactedValue {
+		t.Errorf("hook saw token %v, want it redacted", seen)
+	}
+
+	if strings.Contains(buf.String(), "from-context") {
+		t.Errorf("field added by a hook was not redacted: %s", buf.String())
+	}
+}
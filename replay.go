@@ -0,0 +1,47 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Replay writes previously captured records through into. Each record keeps its original
+// time, level, app, source and data but is encoded with into's format and sent to into's
+// writer, which makes it possible to check a new format or sink against recorded traffic.
+// Records below the level of into are skipped.
+func Replay(records []WriteLog, into *Logger) {
+	for _, rec := range records {
+		level := ToLevel(rec.Level)
+
+		if !into.Enabled(level) {
+			continue
+		}
+
+		into.write(level, rec.copy())
+	}
+}
+
+// ReadRecords decodes newline delimited JSON records, as written by the default formatter,
+// for use with Replay.
+func ReadRecords(r io.Reader) ([]WriteLog, error) {
+	var records []WriteLog
+
+	dec := json.NewDecoder(r)
+
+	for {
+		var rec WriteLog
+
+		err := dec.Decode(&rec)
+
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, rec)
+	}
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReplayThroughAnotherFormat(t *testing.T) {
+	var captured, replayed, direct bytes.Buffer
+
+	logGoldenRecords(goldenLogger(&captured))
+
+	records, err := ReadRecords(&captured)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 4 {
+		t.Fatalf("read %d records, want 4", len(records))
+	}
+
+	into := goldenLogger(&replayed)
+	into.SetFormat(LogfmtFormat)
+	Replay(records, into)
+
+	want := goldenLogger(&direct)
+	want.SetFormat(LogfmtFormat)
+	logGoldenRecords(want)
+
+	if !bytes.Equal(replayed.Bytes(), direct.Bytes()) {
+		t.Errorf("replayed:\n%s\nlogged directly:\n%s", replayed.Bytes(), direct.Bytes())
+	}
+}
+
+func TestReplaySkipsLevelsBelowInto(t *testing.T) {
+	var captured, replayed bytes.Buffer
+
+	logGoldenRecords(goldenLogger(&captured))
+
+	records, err := ReadRecords(&captured)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	into := goldenLogger(&replayed)
+	into.SetLevel(WarningLevel)
+	Replay(records, into)
+
+	got := messages(decodeRecords(t, &replayed))
+
+	if len(got) != 2 || got[0] != "slow request" || got[1] != records[2].Msg {
+		t.Errorf("replayed %q, want the warning and the error", got)
+	}
+}
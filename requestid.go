@@ -0,0 +1,69 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header request ids are read from and echoed back in.
+const RequestIDHeader = "X-Request-Id"
+
+// NewRequestID returns a random version 4 UUID for correlating the records of a request.
+func NewRequestID() string {
+	var id [16]byte
+
+	if _, err := rand.Read(id[:]); err != nil {
+		// crypto/rand does not fail on supported platforms, an id is still better than none
+		return "00000000-0000-4000-8000-000000000000"
+	}
+
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return string(buf[:])
+}
+
+// WithRequestID returns a copy of the logger whose records carry id as request_id.
+func (l *Logger) WithRequestID(id string) *Logger {
+	return l.With(Data{"request_id": id})
+}
+
+// RequestIDMiddleware correlates the records of a request. It takes the id from the
+// X-Request-Id header, or generates one with NewRequestID, echoes it in the response and
+// stores a logger carrying it in the request context, where handlers find it with
+// FromContext:
+//
+//	http.Handle("/", logger.RequestIDMiddleware(handler))
+func (l *Logger) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, r = l.assignRequestID(w, r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// assignRequestID picks the id of r, echoes it in w and returns it with a copy of r whose
+// context carries a logger tagged with it.
+func (l *Logger) assignRequestID(w http.ResponseWriter, r *http.Request) (string, *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+
+	if id == "" {
+		id = NewRequestID()
+	}
+
+	w.Header().Set(RequestIDHeader, id)
+
+	return id, r.WithContext(NewContext(r.Context(), l.WithRequestID(id)))
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+
+	if !uuidPattern.MatchString(a) || a == b {
+		t.Errorf("ids %q and %q, want two distinct version 4 UUIDs", a, b)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	l := NewWithWriter("app", DebugLevel, io.Discard)
+
+	for _, incoming := range []string{"req-42", ""} {
+		var logged any
+
+		handler := l.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logged = recordData(t, FromContext(r.Context()))["request_id"]
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if incoming != "" {
+			req.Header.Set(RequestIDHeader, incoming)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		echoed := rec.Header().Get(RequestIDHeader)
+
+		if incoming != "" && echoed != incoming {
+			t.Errorf("echoed %q, want the incoming %q", echoed, incoming)
+		}
+
+		if incoming == "" && !uuidPattern.MatchString(echoed) {
+			t.Errorf("echoed %q, want a generated UUID", echoed)
+		}
+
+		if logged != echoed {
+			t.Errorf("handler logged request_id %v, response has %q", logged, echoed)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat names backups so that they sort chronologically.
+const rotateTimeFormat = "20060102T150405.000"
+
+// RotatingFileWriter writes records to a file and rolls it over to a timestamped backup, such
+// as app-20060102T150405.000.log, once it grows past a size or age limit. Old backups are
+// pruned by count and age. Write is safe for concurrent use. Loggers do not hold a lock while
+// writing and several loggers may share one writer, so every Write takes the writer's own
+// lock: records never interleave and rotation never races a write.
+type RotatingFileWriter struct {
+	mutex      sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	opened     time.Time
+}
+
+// NewRotatingFileWriter opens path for appending, creating it if needed. The file is rotated
+// once writing would take it past maxSize bytes or once it is older than maxAge. Backups older
+// than maxAge are removed and at most maxBackups are kept. A zero for any limit disables it.
+// Plug the writer into a logger with SetOutput and close it on shutdown.
+func NewRotatingFileWriter(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFileWriter, error) {
+	w := &RotatingFileWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write appends p to the current file, rotating first if p would exceed a limit.
+func (w *RotatingFileWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+
+	if w.shouldRotate(int64(len(p))) {
+		// a failed rotation keeps a file open whenever it can, so the record is still written
+		// and the error returned along with it
+		if rotateErr = w.rotate(); w.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+
+	if err == nil {
+		err = rotateErr
+	}
+
+	return n, err
+}
+
+// Close closes the current file. Further writes fail with os.ErrClosed.
+func (w *RotatingFileWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+func (w *RotatingFileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.opened = time.Now()
+
+	return nil
+}
+
+// shouldRotate reports whether writing n more bytes breaks a limit. A record larger than
+// maxSize is still written, on its own, to a fresh file.
+func (w *RotatingFileWriter) shouldRotate(n int64) bool {
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return true
+	}
+
+	return w.maxAge > 0 && time.Since(w.opened) >= w.maxAge
+}
+
+// rotate moves the current file to a backup and opens a fresh one. The file at path is
+// reopened even when closing or renaming it fails, so logging carries on into it; the next
+// write past a limit tries the rotation again.
+func (w *RotatingFileWriter) rotate() error {
+	closeErr := w.file.Close()
+	w.file = nil
+
+	renameErr := os.Rename(w.path, w.backupName(time.Now()))
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if renameErr != nil {
+		return renameErr
+	}
+
+	return w.prune()
+}
+
+// backupName inserts the rotation time between the file name and its extension. The time is
+// nudged forward if two rotations land in the same millisecond so no backup is overwritten.
+// "logs/app.log" => "logs/app-20060102T150405.000.log"
+func (w *RotatingFileWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+
+	for {
+		name := fmt.Sprintf("%s-%s%s", base, t.UTC().Format(rotateTimeFormat), ext)
+
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// prune removes backups beyond maxBackups, oldest first, and backups older than maxAge.
+func (w *RotatingFileWriter) prune() error {
+	if w.maxBackups <= 0 && w.maxAge <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	backups, err := filepath.Glob(base + "-*" + ext)
+
+	if err != nil {
+		return err
+	}
+
+	// the timestamp format sorts chronologically, so the newest backups come last
+	sort.Strings(backups)
+
+	for i, name := range backups {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, base+"-"), ext)
+		created, err := time.Parse(rotateTimeFormat, stamp)
+
+		if err != nil {
+			continue // not one of ours
+		}
+
+		tooMany := w.maxBackups > 0 && len(backups)-i > w.maxBackups
+		tooOld := w.maxAge > 0 && time.Since(created) > w.maxAge
+
+		if tooMany || tooOld {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
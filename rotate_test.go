@@ -0,0 +1,76 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFileWriterRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingFileWriter(path, 10, 0, 2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := filepath.Glob(filepath.Join(filepath.Dir(path), "app-*.log"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 2 {
+		t.Errorf("kept %d backups, want 2: %v", len(backups), backups)
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "0123456789\n" {
+		t.Errorf("current file holds %q, %v", data, err)
+	}
+}
+
+func TestRotatingFileWriterKeepsWritingWhenRenameFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingFileWriter(path, 12, 0, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	if _, err := w.Write([]byte("first line\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// with the file gone the rename of the next rotation fails
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := w.Write([]byte("second\n")); err == nil || n != len("second\n") {
+		t.Fatalf("Write = %d, %v, want the record written and the rename error", n, err)
+	}
+
+	if _, err := w.Write([]byte("3\n")); err != nil {
+		t.Fatalf("writer stopped after a failed rotation: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "second\n3\n" {
+		t.Errorf("file holds %q", data)
+	}
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// SetSampler caps the volume of repetitive records. Within each one second window the first
+// records with a given level and message are written, after which only every thereafter-th is
+// written and the rest are dropped. When a window that dropped records ends, a "sampled
+// records" summary with the dropped count is written. Windows follow the clock set by
+// SetClock. The sampler is shared by loggers derived from this one. Passing zero for both
+// disables sampling. Records that must never be dropped can be exempted with Always; Alert
+// and Emergency records always are.
+func (l *Logger) SetSampler(first, thereafter int) {
+	var s *sampler
+
+	if first > 0 || thereafter > 0 {
+		s = &sampler{
+			first:      first,
+			thereafter: thereafter,
+			counts:     make(map[samplerKey]int),
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.sampler = s
+}
+
+type samplerKey struct {
+	level Level
+	msg   string
+}
+
+// sampler counts records per level and message within one second windows. Resetting the
+// counts each window keeps memory bounded by the number of distinct messages per second.
+type sampler struct {
+	mutex      sync.Mutex
+	first      int
+	thereafter int
+	window     time.Time
+	counts     map[samplerKey]int
+	dropped    int
+}
+
+// allow reports whether a record should be written. When now starts a new window, it also
+// returns the number of records dropped during the previous one.
+func (s *sampler) allow(level Level, msg string, now time.Time) (ok bool, dropped int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.window) >= time.Second {
+		dropped = s.dropped
+		s.window = now
+		s.dropped = 0
+
+		for key := range s.counts {
+			delete(s.counts, key)
+		}
+	}
+
+	key := samplerKey{level: level, msg: msg}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true, dropped
+	}
+
+	s.dropped++
+
+	return false, dropped
+}
+
+// Always returns a copy of the logger whose records bypass sampling, rate limiting and
+// SetDedup, for events such as security alerts or payment failures that must be written
+// however busy the logger is:
+//
+//	logger.Always().With(log.Data{"order": id}).Error("payment failed")
+//
+// Loggers derived from the copy are exempt as well. Records at AlertLevel and above are
+// exempt from any logger. Exempt records do not use up the allowance of any limit.
+func (l *Logger) Always() *Logger {
+	child := l.clone()
+	child.opts.always = true
+
+	return child
+}
+
+// exempt reports whether a record at level skips sampling, rate limiting and dedup.
+func (l *Logger) exempt(level Level) bool {
+	if level >= AlertLevel {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.opts.always
+}
+
+// sampled consults the sampler, if any, and writes the summary for a finished window.
+func (l *Logger) sampled(level Level, msg string) bool {
+	l.mutex.Lock()
+	s := l.opts.sampler
+	clock := l.opts.clock
+	l.mutex.Unlock()
+
+	if s == nil {
+		return true
+	}
+
+	ok, dropped := s.allow(level, msg, now(clock))
+
+	if dropped > 0 {
+		l.writeInternal(WarningLevel, "sampled records", Data{"dropped": dropped})
+	}
+
+	return ok
+}
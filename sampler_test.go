@@ -0,0 +1,153 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for SetClock that only moves when told to.
+type fakeClock struct {
+	mutex sync.Mutex
+	t     time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: goldenTime}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+// decodeRecords parses the records written to buf, one per line.
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []WriteLog {
+	t.Helper()
+
+	var out []WriteLog
+
+	dec := json.NewDecoder(buf)
+
+	for dec.More() {
+		var rec WriteLog
+
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+
+		out = append(out, rec)
+	}
+
+	return out
+}
+
+// messages returns the message of each record.
+func messages(records []WriteLog) []string {
+	out := make([]string, len(records))
+
+	for i, rec := range records {
+		out[i] = rec.Msg
+	}
+
+	return out
+}
+
+func TestSamplerRatio(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := newFakeClock()
+	l := goldenLogger(&buf)
+	l.SetClock(clock.Now)
+	l.SetSampler(2, 3)
+
+	for i := 0; i < 10; i++ {
+		l.Info("tick")
+	}
+
+	l.Info("other")
+	l.Warn("tick")
+
+	// first 2, then the 5th and 8th; other messages and levels are counted apart
+	if got := decodeRecords(t, &buf); len(got) != 6 {
+		t.Fatalf("wrote %v, want 4 ticks, other and the warning", messages(got))
+	}
+
+	clock.Advance(time.Second)
+	l.Info("tick")
+
+	got := decodeRecords(t, &buf)
+
+	if len(got) != 2 || got[0].Msg != "sampled records" || got[0].Data["dropped"] != float64(6) {
+		t.Fatalf("next window wrote %+v, want the summary of 6 dropped records then tick", got)
+	}
+
+	if got[1].Msg != "tick" {
+		t.Errorf("the first record of a new window was dropped: %v", messages(got))
+	}
+}
+
+func TestSamplerDisabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetSampler(1, 0)
+	l.SetSampler(0, 0)
+
+	for i := 0; i < 5; i++ {
+		l.Info("tick")
+	}
+
+	if got := decodeRecords(t, &buf); len(got) != 5 {
+		t.Errorf("wrote %d records with sampling disabled, want 5", len(got))
+	}
+}
+
+func TestAlwaysIsExempt(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := goldenLogger(&buf)
+	l.SetClock(newFakeClock().Now)
+	l.SetSampler(1, 0)
+	l.SetRateLimit(1)
+	l.SetDedup(time.Hour)
+
+	always := l.Always().With(Data{"order": 7})
+
+	for i := 0; i < 5; i++ {
+		always.Error("payment failed")
+	}
+
+	if got := decodeRecords(t, &buf); len(got) != 5 {
+		t.Fatalf("Always wrote %d of 5 records", len(got))
+	}
+
+	// exempt records leave the allowance of every limit to the others
+	l.Error("payment failed")
+	l.Error("payment failed")
+	l.Info("other")
+
+	if got := messages(decodeRecords(t, &buf)); !reflect.DeepEqual(got, []string{"payment failed"}) {
+		t.Errorf("the filtered logger wrote %v, want the first record only", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.Alert("disk failing")
+	}
+
+	if got := decodeRecords(t, &buf); len(got) != 3 {
+		t.Errorf("wrote %d of 3 alert records", len(got))
+	}
+}
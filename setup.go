@@ -4,9 +4,12 @@ import (
 	"os"
 )
 
-// SetupLogger handles the standard logger setup for lambda services.
+// SetupLogger handles the standard logger setup for lambda services. Defer Close on the
+// returned logger so records held by asynchronous writers are delivered before the process
+// exits, and call Drain before each handler returns, since the runtime may freeze the process
+// with records still in flight.
 func SetupLogger(name, build string) *Logger {
 	var logLevel = ToLevel(os.Getenv("LOG_LEVEL"))
 
-	return New(name, logLevel).With(Data{"build": build})
+	return New(name, logLevel, Data{"build": build})
 }
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package log
+
+// InstallSignalHandlers does nothing on platforms without SIGUSR1 and SIGUSR2.
+func (l *Logger) InstallSignalHandlers() {}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// InstallSignalHandlers lets operators change the level of a running process without an
+// admin endpoint: SIGUSR1 switches the logger to DebugLevel and SIGUSR2 restores the level it
+// had before. Every change is recorded with a notice. Only the first call installs the
+// handlers; later calls do nothing.
+func (l *Logger) InstallSignalHandlers() {
+	l.signalOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+		go func() {
+			for sig := range signals {
+				l.handleSignal(sig)
+			}
+		}()
+	})
+}
+
+// handleSignal applies the level change requested by sig.
+func (l *Logger) handleSignal(sig os.Signal) {
+	l.mutex.Lock()
+
+	switch sig {
+	case syscall.SIGUSR1:
+		if !l.signalRaised {
+			l.signalRestore = l.Level()
+			l.signalRaised = true
+		}
+
+		l.SetLevel(DebugLevel)
+	case syscall.SIGUSR2:
+		if l.signalRaised {
+			l.SetLevel(l.signalRestore)
+			l.signalRaised = false
+		}
+	default:
+		l.mutex.Unlock()
+		return
+	}
+
+	l.mutex.Unlock()
+
+	l.writeInternal(NoticeLevel, "log level changed", Data{
+		"level":  l.Level().String(),
+		"signal": sig.String(),
+	})
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package log
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitForLevel waits until l is at level, failing the test after a few seconds.
+func waitForLevel(t *testing.T, l *Logger, level Level) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for l.Level() != level {
+		if time.Now().After(deadline) {
+			t.Fatalf("level is %s, want %s", l.Level(), level)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSignalsToggleDebug(t *testing.T) {
+	var buf lockedBuffer
+
+	l := NewWithWriter("app", WarningLevel, &buf)
+	l.InstallSignalHandlers()
+	l.InstallSignalHandlers()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForLevel(t, l, DebugLevel)
+
+	// a second SIGUSR1 keeps the level to restore
+	l.handleSignal(syscall.SIGUSR1)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForLevel(t, l, WarningLevel)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !buf.Contains(`"level":"warning","signal":"user defined signal 2"`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("level changes were not recorded: %s", buf.String())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if !buf.Contains(`"msg":"log level changed","data":{"level":"debug","signal":"user defined signal 1"}`) {
+		t.Errorf("the switch to debug was not recorded: %s", buf.String())
+	}
+}
+
+func TestSignalRestoreWithoutRaise(t *testing.T) {
+	l := NewWithWriter("app", ErrorLevel, &lockedBuffer{})
+	l.handleSignal(syscall.SIGUSR2)
+
+	if l.Level() != ErrorLevel {
+		t.Errorf("SIGUSR2 without SIGUSR1 changed the level to %s", l.Level())
+	}
+}
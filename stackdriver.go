@@ -0,0 +1,68 @@
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+var stackdriverSeverities = map[string]string{
+	"debug":     "DEBUG",
+	"info":      "INFO",
+	"notice":    "NOTICE",
+	"warning":   "WARNING",
+	"error":     "ERROR",
+	"critical":  "CRITICAL",
+	"alert":     "ALERT",
+	"emergency": "EMERGENCY",
+}
+
+// StackdriverFormatter encodes records in the structured layout understood by the Google Cloud
+// Logging agent: the level becomes "severity", msg becomes "message" and the source location
+// moves to "logging.googleapis.com/sourceLocation". Use it with SetFormatter.
+type StackdriverFormatter struct{}
+
+type stackdriverRecord struct {
+	Time     time.Time          `json:"time"`
+	Severity string             `json:"severity"`
+	Message  string             `json:"message"`
+	App      string             `json:"app,omitempty"`
+	Host     string             `json:"host,omitempty"`
+	PID      int                `json:"pid,omitempty"`
+	Data     Data               `json:"data,omitempty"`
+	Source   *stackdriverSource `json:"logging.googleapis.com/sourceLocation,omitempty"`
+}
+
+type stackdriverSource struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+// Format implements Formatter.
+func (StackdriverFormatter) Format(rec WriteLog) ([]byte, error) {
+	severity, ok := stackdriverSeverities[rec.Level]
+
+	if !ok {
+		severity = "DEFAULT"
+	}
+
+	doc := stackdriverRecord{
+		Time:     rec.Time,
+		Severity: severity,
+		Message:  rec.Msg,
+		App:      rec.App,
+		Host:     rec.Host,
+		PID:      rec.PID,
+		Data:     rec.Data,
+	}
+
+	if !rec.Src.IsZero() {
+		doc.Source = &stackdriverSource{
+			File: rec.Src.Path(),
+			// the LogEntrySourceLocation schema encodes line as an int64 string
+			Line: strconv.Itoa(rec.Src.Line),
+		}
+	}
+
+	return json.Marshal(doc)
+}
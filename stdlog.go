@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	stdlog "log"
+	"sync"
+)
+
+// StdLogger returns a standard library logger whose output becomes records of l at level, so
+// libraries that only accept a *log.Logger feed the structured pipeline. The standard
+// library's own prefix and timestamp are disabled; each Print call is one record, with src
+// pointing at the caller of Print.
+//
+//	server := &http.Server{ErrorLog: logger.StdLogger(log.ErrorLevel)}
+func (l *Logger) StdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(stdWriter{logger: l, level: level}, "", 0)
+}
+
+// stdWriter turns the lines written by a standard library logger into records.
+type stdWriter struct {
+	logger *Logger
+	level  Level
+}
+
+// stdCallDepth skips the standard library frames between a Print call and Write.
+const stdCallDepth = 4
+
+func (w stdWriter) Write(p []byte) (n int, err error) {
+	w.logger.output(stdCallDepth, w.level, string(bytes.TrimRight(p, "\n")), nil)
+	return len(p), nil
+}
+
+// WriterAt returns a writer that turns each line written to it into a record of l at level,
+// with the line as the message, for libraries that log to a plain io.Writer. Writes may split
+// or join lines freely: text is buffered until its newline arrives, and a write holding
+// several lines becomes several records. Empty lines are dropped. The writer implements
+// Flusher, and Flush writes out a final line that lacks its newline. The records carry no src,
+// which would only point into the library doing the writing.
+func (l *Logger) WriterAt(level Level) io.Writer {
+	logger := l.With()
+	logger.SetIncludeSource(false)
+
+	return &lineWriter{logger: logger, level: level}
+}
+
+// lineWriter buffers partial lines for WriterAt.
+type lineWriter struct {
+	mutex   sync.Mutex
+	logger  *Logger
+	level   Level
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.partial = append(w.partial, p...)
+
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+
+		if i < 0 {
+			break
+		}
+
+		w.emit(w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+
+	// keep the buffer from holding on to everything ever written
+	if len(w.partial) == 0 {
+		w.partial = nil
+	}
+
+	return len(p), nil
+}
+
+// Flush writes out a buffered line that has no newline yet.
+func (w *lineWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.partial) > 0 {
+		w.emit(w.partial)
+		w.partial = nil
+	}
+
+	return nil
+}
+
+func (w *lineWriter) emit(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+
+	if len(line) == 0 {
+		return
+	}
+
+	w.logger.output(3, w.level, string(line), nil)
+}
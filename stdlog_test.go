@@ -0,0 +1,75 @@
+package log
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf).With(Data{"component": "http"})
+	std := l.StdLogger(ErrorLevel)
+
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("accept failed: %s", "timeout")
+	std.Print("second\n")
+
+	records := decodeRecords(t, &buf)
+
+	if got := messages(records); len(got) != 2 || got[0] != "accept failed: timeout" || got[1] != "second" {
+		t.Fatalf("wrote %q, want one record per Print", got)
+	}
+
+	rec := records[0]
+
+	if rec.Level != "error" || rec.Data["component"] != "http" {
+		t.Errorf("record = %s %v, want error with the logger's data", rec.Level, rec.Data)
+	}
+
+	if filepath.Base(rec.Src.File) != "stdlog_test.go" || rec.Src.Line != line+1 {
+		t.Errorf("src = %s:%d, want stdlog_test.go:%d", rec.Src.File, rec.Src.Line, line+1)
+	}
+
+	l.SetLevel(CriticalLevel)
+	std.Print("filtered")
+
+	if buf.Len() != 0 {
+		t.Errorf("wrote %s below the logger's level", buf.Bytes())
+	}
+}
+
+func TestWriterAt(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewWithWriter("app", DebugLevel, &buf)
+	w := l.WriterAt(WarningLevel)
+
+	for _, chunk := range []string{"first li", "ne\nsecond\r\n\nthi", "rd\nunfinished"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := decodeRecords(t, &buf)
+
+	if got := messages(records); len(got) != 3 || got[0] != "first line" || got[1] != "second" || got[2] != "third" {
+		t.Errorf("wrote %q, want the three complete lines", got)
+	}
+
+	for _, rec := range records {
+		if rec.Level != "warning" || rec.Src.File != "" {
+			t.Errorf("record %q = %s with src %+v, want warning without src", rec.Msg, rec.Level, rec.Src)
+		}
+	}
+
+	if err := w.(Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := messages(decodeRecords(t, &buf)); len(got) != 1 || got[0] != "unfinished" {
+		t.Errorf("Flush wrote %q, want the unfinished line", got)
+	}
+}
@@ -0,0 +1,278 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// KeyConvention controls how the fields of structs logged in data are named.
+type KeyConvention int
+
+const (
+	// AsIs leaves struct encoding to encoding/json. This is the default.
+	AsIs KeyConvention = iota
+	// SnakeCase names untagged struct fields in snake_case ("UserID" => "user_id"). Fields with
+	// a json tag keep the tagged name.
+	SnakeCase
+)
+
+// SetStructKeyConvention selects how struct values in data are named when encoded. Domain
+// structs rarely carry json tags, so without this they are logged with Go's CamelCase names.
+func (l *Logger) SetStructKeyConvention(convention KeyConvention) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opts.keyConvention = convention
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// snakeCaseValue rewrites structs reachable from value into maps keyed by snake_case names.
+// Types that marshal themselves are left untouched. Like the walk of resolveLazy, it stops at
+// limit levels of nesting and at pointers, maps and slices met again below themselves,
+// writing the same placeholders, so self referencing structs are safe to log.
+func snakeCaseValue(value any, limit int) any {
+	if value == nil {
+		return nil
+	}
+
+	walk := keyWalk{limit: limit, path: map[visit]bool{}}
+
+	return walk.value(reflect.ValueOf(value), 1)
+}
+
+// keyWalk holds the state of one snakeCaseValue pass. path records the pointers, maps and
+// slices being descended into.
+type keyWalk struct {
+	limit int
+	path  map[visit]bool
+}
+
+// visit identifies a pointer, map or slice by what it points to. The type and length tell
+// apart a struct and its first field, or a slice and a shorter slice of it, which share an
+// address.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks v as being descended into and reports false when it already is, which means v
+// contains itself.
+func (w *keyWalk) enter(v reflect.Value) (visit, bool) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+
+	if w.path[key] {
+		return key, false
+	}
+
+	w.path[key] = true
+
+	return key, true
+}
+
+func (w *keyWalk) value(v reflect.Value, depth int) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		key, ok := w.enter(v)
+
+		if !ok {
+			return cycleValue
+		}
+
+		defer delete(w.path, key)
+
+		return w.value(v.Elem(), depth)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return w.value(v.Elem(), depth)
+	case reflect.Struct:
+		if depth > w.limit {
+			return maxDepthValue
+		}
+
+		set := make(map[string]any, v.NumField())
+		w.structFields(set, v, depth)
+		return set
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+
+		if v.IsNil() {
+			return nil
+		}
+
+		if depth > w.limit {
+			return maxDepthValue
+		}
+
+		key, ok := w.enter(v)
+
+		if !ok {
+			return cycleValue
+		}
+
+		defer delete(w.path, key)
+
+		set := make(map[string]any, v.Len())
+		iter := v.MapRange()
+
+		for iter.Next() {
+			set[iter.Key().String()] = w.value(iter.Value(), depth+1)
+		}
+
+		return set
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // []byte is encoded as base64
+		}
+
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		if depth > w.limit {
+			return maxDepthValue
+		}
+
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			key, ok := w.enter(v)
+
+			if !ok {
+				return cycleValue
+			}
+
+			defer delete(w.path, key)
+		}
+
+		list := make([]any, v.Len())
+
+		for i := range list {
+			list[i] = w.value(v.Index(i), depth+1)
+		}
+
+		return list
+	}
+
+	return v.Interface()
+}
+
+// structFields copies the exported fields of v into set following the encoding/json rules
+// for tags, omitempty and untagged embedded structs. Embedded fields are at the depth of v.
+func (w *keyWalk) structFields(set map[string]any, v reflect.Value, depth int) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			embedded := value
+
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				w.embedded(set, value, embedded, depth)
+				continue
+			}
+
+			value = embedded
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if strings.Contains(opts, "omitempty") && value.IsZero() {
+			continue
+		}
+
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+
+		set[name] = w.value(value, depth+1)
+	}
+}
+
+// embedded copies the fields of the struct embedded as field into set. An embedded pointer
+// back to a struct being copied adds nothing.
+func (w *keyWalk) embedded(set map[string]any, field, embedded reflect.Value, depth int) {
+	if field.Kind() == reflect.Pointer {
+		key, ok := w.enter(field)
+
+		if !ok {
+			return
+		}
+
+		defer delete(w.path, key)
+	}
+
+	w.structFields(set, embedded, depth)
+}
+
+// toSnakeCase converts a Go identifier to snake_case, keeping initialisms together.
+//
+// "UserID" => "user_id", "HTTPServer" => "http_server", "CreatedAt" => "created_at"
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	b.Grow(len(name) + 4)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
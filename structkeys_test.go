@@ -0,0 +1,113 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type keyNode struct {
+	NodeID int
+	Next   *keyNode
+	Attrs  map[string]any
+}
+
+func snakeCaseJSON(t *testing.T, value any, limit int) string {
+	t.Helper()
+
+	data, err := json.Marshal(snakeCaseValue(value, limit))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestSnakeCaseValue(t *testing.T) {
+	type Account struct {
+		UserID    int
+		HTTPProxy string
+		Tagged    string `json:"custom"`
+		Skipped   string `json:"-"`
+		Empty     string `json:",omitempty"`
+	}
+
+	got := snakeCaseJSON(t, Account{UserID: 7, HTTPProxy: "p", Tagged: "t", Skipped: "s"}, defaultMaxDepth)
+	want := `{"custom":"t","http_proxy":"p","user_id":7}`
+
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSnakeCaseSelfReferencingStruct(t *testing.T) {
+	n := &keyNode{NodeID: 1}
+	n.Next = n
+
+	got := snakeCaseJSON(t, n, defaultMaxDepth)
+	want := `{"attrs":null,"next":"[truncated: cycle]","node_id":1}`
+
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSnakeCaseSelfReferencingMap(t *testing.T) {
+	attrs := map[string]any{}
+	attrs["self"] = attrs
+
+	got := snakeCaseJSON(t, keyNode{NodeID: 1, Attrs: attrs}, defaultMaxDepth)
+	want := `{"attrs":{"self":"[truncated: cycle]"},"next":null,"node_id":1}`
+
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSnakeCaseSharedPointerIsNotACycle(t *testing.T) {
+	shared := &keyNode{NodeID: 2}
+
+	got := snakeCaseValue([]*keyNode{shared, shared}, defaultMaxDepth)
+	want := []any{
+		map[string]any{"node_id": 2, "next": nil, "attrs": nil},
+		map[string]any{"node_id": 2, "next": nil, "attrs": nil},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSnakeCaseMaxDepth(t *testing.T) {
+	var head *keyNode
+
+	for i := 5; i > 0; i-- {
+		head = &keyNode{NodeID: i, Next: head}
+	}
+
+	got := snakeCaseJSON(t, head, 2)
+	want := `{"attrs":null,"next":{"attrs":null,"next":"[truncated: max depth]","node_id":2},"node_id":1}`
+
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestStructKeyConventionWithCycle(t *testing.T) {
+	var buf bytes.Buffer
+
+	n := &keyNode{NodeID: 1}
+	n.Next = n
+
+	l := goldenLogger(&buf)
+	l.SetStructKeyConvention(SnakeCase)
+	l.With(Data{"node": n}).Info("cycle")
+
+	want := `"data":{"node":{"attrs":null,"next":"[truncated: cycle]","node_id":1}}`
+
+	if !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("got %s, want it to contain %s", buf.Bytes(), want)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows || plan9
+
+package log
+
+import "errors"
+
+var errSyslogUnsupported = errors.New("log: syslog is not supported on this platform")
+
+// SyslogWriter is not supported on this platform. NewSyslogWriter always fails.
+type SyslogWriter struct{}
+
+// NewSyslogWriter always returns an error on this platform.
+func NewSyslogWriter(network, raddr, tag string) (*SyslogWriter, error) {
+	return nil, errSyslogUnsupported
+}
+
+// SetFormatter does nothing on this platform.
+func (s *SyslogWriter) SetFormatter(f Formatter) {}
+
+// WriteRecord implements RecordWriter.
+func (s *SyslogWriter) WriteRecord(rec WriteLog) error {
+	return errSyslogUnsupported
+}
+
+// Write always fails on this platform.
+func (s *SyslogWriter) Write(p []byte) (n int, err error) {
+	return 0, errSyslogUnsupported
+}
+
+// Close does nothing on this platform.
+func (s *SyslogWriter) Close() error {
+	return nil
+}
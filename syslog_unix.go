@@ -0,0 +1,91 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"log/syslog"
+	"sync"
+)
+
+// SyslogWriter sends records to a syslog daemon, mapping each record's level to the matching
+// syslog priority; custom levels above EmergencyLevel are sent as emergencies. It implements RecordWriter so the priority can be chosen per record.
+type SyslogWriter struct {
+	mutex     sync.Mutex
+	writer    *syslog.Writer
+	formatter Formatter
+}
+
+// NewSyslogWriter connects to the syslog daemon at raddr over network, using the user
+// facility and tag. An empty network and raddr connect to the local daemon. See syslog.Dial.
+func NewSyslogWriter(network, raddr, tag string) (*SyslogWriter, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_USER, tag)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogWriter{writer: w, formatter: JSONFormatter{}}, nil
+}
+
+// SetFormatter changes how the record is encoded in the syslog message. The default is JSON.
+func (s *SyslogWriter) SetFormatter(f Formatter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.formatter = f
+}
+
+// WriteRecord implements RecordWriter.
+func (s *SyslogWriter) WriteRecord(rec WriteLog) error {
+	s.mutex.Lock()
+	formatter := s.formatter
+	s.mutex.Unlock()
+
+	data, err := formatter.Format(rec)
+
+	if err != nil {
+		return err
+	}
+
+	msg := string(data)
+
+	switch syslogSeverity(ToLevel(rec.Level)) {
+	case syslog.LOG_EMERG:
+		return s.writer.Emerg(msg)
+	case syslog.LOG_ALERT:
+		return s.writer.Alert(msg)
+	case syslog.LOG_CRIT:
+		return s.writer.Crit(msg)
+	case syslog.LOG_ERR:
+		return s.writer.Err(msg)
+	case syslog.LOG_WARNING:
+		return s.writer.Warning(msg)
+	case syslog.LOG_NOTICE:
+		return s.writer.Notice(msg)
+	case syslog.LOG_INFO:
+		return s.writer.Info(msg)
+	default:
+		return s.writer.Debug(msg)
+	}
+}
+
+// syslogSeverity returns the syslog severity of level. Custom levels outside the built-in
+// range take the nearest built-in severity, as for SyslogLevelNumber.
+func syslogSeverity(level Level) syslog.Priority {
+	severity, _ := SyslogLevelNumber.Number(level)
+
+	return syslog.Priority(severity)
+}
+
+// Write sends already encoded bytes at the info priority.
+func (s *SyslogWriter) Write(p []byte) (n int, err error) {
+	if err = s.writer.Info(string(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (s *SyslogWriter) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,75 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  syslog.Priority
+	}{
+		{DebugLevel, syslog.LOG_DEBUG},
+		{InfoLevel, syslog.LOG_INFO},
+		{NoticeLevel, syslog.LOG_NOTICE},
+		{WarningLevel, syslog.LOG_WARNING},
+		{ErrorLevel, syslog.LOG_ERR},
+		{CriticalLevel, syslog.LOG_CRIT},
+		{AlertLevel, syslog.LOG_ALERT},
+		{EmergencyLevel, syslog.LOG_EMERG},
+		{EmergencyLevel + 5, syslog.LOG_EMERG},
+		{DebugLevel - 1, syslog.LOG_DEBUG},
+	}
+
+	for _, tt := range tests {
+		if got := syslogSeverity(tt.level); got != tt.want {
+			t.Errorf("syslogSeverity(%d) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogWriterPriorities(t *testing.T) {
+	daemon, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Skipf("no local UDP socket: %v", err)
+	}
+
+	defer daemon.Close()
+
+	w, err := NewSyslogWriter("udp", daemon.LocalAddr().String(), "test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer w.Close()
+
+	for _, level := range []Level{ErrorLevel, InfoLevel, EmergencyLevel} {
+		if err := w.WriteRecord(WriteLog{Level: level.String(), Msg: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+
+		packet := make([]byte, 2048)
+		_ = daemon.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := daemon.ReadFrom(packet)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the priority is the facility and severity combined, <11> for a user error
+		want := fmt.Sprintf("<%d>", syslog.LOG_USER|syslogSeverity(level))
+
+		if got := string(packet[:n]); !strings.HasPrefix(got, want) || !strings.Contains(got, `"msg":"hello"`) {
+			t.Errorf("%s record sent as %q, want priority %s", level, got, want)
+		}
+	}
+}
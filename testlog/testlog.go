@@ -0,0 +1,114 @@
+// Package testlog captures the records written by a log.Logger so tests can assert on what
+// the code under test logged.
+//
+//	logger, sink := testlog.New()
+//	handler(logger).ServeHTTP(w, r)
+//
+//	if !sink.ContainsMsg(log.ErrorLevel, "upstream timeout") {
+//		t.Fatalf("expected an upstream timeout error, got %v", sink.Records())
+//	}
+package testlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/crit/log"
+)
+
+// Sink stores every record written to it. It is safe for concurrent use.
+type Sink struct {
+	mutex   sync.Mutex
+	records []log.WriteLog
+}
+
+// New returns a logger that writes every level to a new Sink.
+func New() (*log.Logger, *Sink) {
+	sink := &Sink{}
+	logger := log.New("test", log.DebugLevel)
+	logger.SetOutput(sink)
+
+	return logger, sink
+}
+
+// WriteRecord implements log.RecordWriter, storing rec as the logger built it.
+func (s *Sink) WriteRecord(rec log.WriteLog) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = append(s.records, rec)
+
+	return nil
+}
+
+// Write parses JSON encoded records, one per line, so the Sink can also sit behind writers
+// that deal in bytes.
+func (s *Sink) Write(p []byte) (n int, err error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+
+	for dec.More() {
+		var rec log.WriteLog
+
+		if err = dec.Decode(&rec); err != nil {
+			return 0, err
+		}
+
+		_ = s.WriteRecord(rec)
+	}
+
+	return len(p), nil
+}
+
+// Records returns a copy of all records written so far, oldest first.
+func (s *Sink) Records() []log.WriteLog {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]log.WriteLog(nil), s.records...)
+}
+
+// Len returns the number of records written so far.
+func (s *Sink) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.records)
+}
+
+// Last returns the most recent record, or the zero WriteLog when nothing was written.
+func (s *Sink) Last() log.WriteLog {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.records) == 0 {
+		return log.WriteLog{}
+	}
+
+	return s.records[len(s.records)-1]
+}
+
+// ContainsMsg reports whether a record at level has a message containing substr.
+func (s *Sink) ContainsMsg(level log.Level, substr string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	label := level.String()
+
+	for _, rec := range s.records {
+		if rec.Level == label && strings.Contains(rec.Msg, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reset discards all stored records.
+func (s *Sink) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = nil
+}
@@ -0,0 +1,130 @@
+package testlog
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/crit/log"
+)
+
+func TestSinkCapturesRecords(t *testing.T) {
+	logger, sink := New()
+
+	if sink.Len() != 0 || !sink.Last().Src.IsZero() || sink.Last().Msg != "" {
+		t.Fatalf("new sink holds %v", sink.Records())
+	}
+
+	logger.With(log.Data{"user": 42}).Info("saved")
+	logger.Error("failed: %s", "timeout")
+
+	records := sink.Records()
+
+	if len(records) != 2 || sink.Len() != 2 {
+		t.Fatalf("captured %d records, want 2", len(records))
+	}
+
+	if records[0].Msg != "saved" || records[0].Level != "info" || records[0].Data["user"] != 42 {
+		t.Errorf("first record = %+v", records[0])
+	}
+
+	if last := sink.Last(); last.Msg != "failed: timeout" || last.Level != "error" {
+		t.Errorf("last record = %+v", last)
+	}
+
+	records[0].Msg = "changed"
+
+	if sink.Records()[0].Msg != "saved" {
+		t.Error("Records returned the sink's own slice")
+	}
+
+	sink.Reset()
+
+	if sink.Len() != 0 {
+		t.Errorf("Reset left %d records", sink.Len())
+	}
+}
+
+func TestContainsMsg(t *testing.T) {
+	logger, sink := New()
+
+	logger.Warn("upstream timeout after 3 tries")
+
+	tests := []struct {
+		level  log.Level
+		substr string
+		want   bool
+	}{
+		{log.WarningLevel, "upstream timeout", true},
+		{log.WarningLevel, "", true},
+		{log.ErrorLevel, "upstream timeout", false},
+		{log.WarningLevel, "downstream", false},
+	}
+
+	for _, tt := range tests {
+		if got := sink.ContainsMsg(tt.level, tt.substr); got != tt.want {
+			t.Errorf("ContainsMsg(%s, %q) = %v, want %v", tt.level, tt.substr, got, tt.want)
+		}
+	}
+}
+
+func TestSinkFollowsLoggerLevel(t *testing.T) {
+	logger, sink := New()
+	logger.SetLevel(log.WarningLevel)
+
+	logger.Debug("hidden")
+	logger.Info("hidden")
+	logger.Warn("shown")
+	logger.Error("shown")
+
+	if sink.Len() != 2 {
+		t.Fatalf("captured %v, want the warning and error only", sink.Records())
+	}
+
+	if sink.ContainsMsg(log.InfoLevel, "hidden") {
+		t.Error("a filtered record was captured")
+	}
+}
+
+func TestSinkWriteDecodesLines(t *testing.T) {
+	sink := &Sink{}
+	p := []byte(`{"level":"info","msg":"one"}` + "\n" + `{"level":"error","msg":"two","data":{"n":1}}` + "\n")
+
+	n, err := sink.Write(p)
+
+	if err != nil || n != len(p) {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	if sink.Len() != 2 || !sink.ContainsMsg(log.ErrorLevel, "two") {
+		t.Errorf("decoded %+v", sink.Records())
+	}
+
+	if _, err := sink.Write([]byte("not json")); err == nil {
+		t.Error("Write accepted a malformed record")
+	}
+}
+
+func TestSinkConcurrentUse(t *testing.T) {
+	logger, sink := New()
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				logger.Info("record")
+				_ = sink.Len()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if sink.Len() != 200 {
+		t.Errorf("captured %d records, want 200", sink.Len())
+	}
+}
@@ -2,9 +2,14 @@ package log
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,35 +22,384 @@ var client = &http.Client{
 	},
 }
 
-type postWriter string
+// Defaults for the PostWriter worker pool.
+const (
+	defaultPostWorkers = 4
+	postQueueSize      = 1024
+	defaultPostBatch   = 100
+)
+
+// PostBody selects how a PostWriter lays out records in a request body, to match what the
+// collector accepts.
+type PostBody int
+
+const (
+	// PostSingle sends each record in a request of its own, the JSON object being the whole
+	// body. This is the default.
+	PostSingle PostBody = iota
+	// PostNDJSON sends queued records together, one JSON object per line, with a
+	// Content-Type of application/x-ndjson.
+	PostNDJSON
+	// PostArray sends queued records together as a single JSON array.
+	PostArray
+)
+
+// PostWriter sends records to a remote collector in HTTP POSTs, one per request unless SetBody
+// says otherwise, and echoes them to stdout. Requests are made in the background by a fixed
+// pool of workers so a slow collector never blocks logging; records that arrive while the
+// queue is full are dropped and counted. Failures are reported through the standard library
+// logger unless an error handler is set. Flush waits for queued records to be delivered and
+// Close does the same before stopping the workers.
+type PostWriter struct {
+	url     string
+	mutex   sync.Mutex
+	ctx     context.Context
+	onError func(error)
+	gzip    bool
+	body    PostBody
+	batch   int
+	workers int
+	closed  bool
+	pending int
+	idle    *sync.Cond
+	start   sync.Once
+	queue   chan postJob
+	dropped atomic.Int64
+}
+
+type postJob struct {
+	body     []byte
+	compress bool
+}
+
+// NewPostWriter returns a writer that posts records to url. An empty url only echoes records
+// to stdout.
+func NewPostWriter(url string) *PostWriter {
+	w := &PostWriter{url: url, ctx: context.Background(), workers: defaultPostWorkers, batch: defaultPostBatch}
+	w.idle = sync.NewCond(&w.mutex)
+
+	return w
+}
+
+// SetConcurrency caps the number of requests in flight at once. It must be called before the
+// first Write; the pool is fixed once it has started. Values below one are ignored.
+func (w *PostWriter) SetConcurrency(n int) {
+	if n < 1 {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.workers = n
+}
+
+// SetContext makes every request, including those already queued, use ctx. Cancelling it
+// aborts posts in flight and makes queued ones fail at once, so a shutdown signal can cut
+// delivery short and let Flush and Close return promptly. Deadlines on ctx apply to each
+// request on top of the client timeout.
+func (w *PostWriter) SetContext(ctx context.Context) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.ctx = ctx
+}
+
+// SetErrorHandler routes delivery failures, including context errors from a cancelled
+// SetContext, to fn instead of the standard library logger. fn is called from the worker
+// goroutines.
+func (w *PostWriter) SetErrorHandler(fn func(error)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onError = fn
+}
+
+// Dropped returns the number of records discarded because the queue was full.
+func (w *PostWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Pending returns the number of records queued or being posted.
+func (w *PostWriter) Pending() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.pending
+}
+
+// SetGzip turns gzip compression of request bodies on or off. Compressed requests carry a
+// Content-Encoding: gzip header. It is off by default.
+func (w *PostWriter) SetGzip(enabled bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.gzip = enabled
+}
+
+// SetBody selects the layout of request bodies. With PostNDJSON and PostArray a worker sends
+// the records already queued when it picks up a record, up to batch of them, in one request,
+// so a busy logger makes far fewer requests; a quiet one still sends records as they come.
+// A batch below one keeps the current size, 100 by default. It is ignored for PostSingle.
+func (w *PostWriter) SetBody(body PostBody, batch int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.body = body
+
+	if batch > 0 {
+		w.batch = batch
+	}
+}
+
+func (w *PostWriter) Write(p []byte) (n int, err error) {
+	if w.url != "" {
+		w.start.Do(w.startWorkers)
+
+		w.mutex.Lock()
+
+		if w.closed {
+			w.mutex.Unlock()
+			return 0, os.ErrClosed
+		}
+
+		// p is only valid until Write returns, copy it for the request
+		job := postJob{body: append([]byte(nil), p...), compress: w.gzip}
+
+		select {
+		case w.queue <- job:
+			w.pending++
+		default:
+			w.dropped.Add(1)
+		}
+
+		w.mutex.Unlock()
+	}
+
+	return os.Stdout.Write(p)
+}
+
+// Flush blocks until every queued record has been posted.
+func (w *PostWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for w.pending > 0 {
+		w.idle.Wait()
+	}
+
+	return nil
+}
+
+// Close waits for queued records to be posted and stops the workers. Further writes fail with
+// os.ErrClosed.
+func (w *PostWriter) Close() error {
+	w.mutex.Lock()
+
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+
+	w.closed = true
+	w.mutex.Unlock()
+
+	// keep a writer that never posted from starting its workers
+	w.start.Do(func() {})
+
+	if w.queue != nil {
+		close(w.queue)
+	}
+
+	return w.Flush()
+}
+
+func (w *PostWriter) startWorkers() {
+	w.mutex.Lock()
+	workers := w.workers
+	w.mutex.Unlock()
+
+	w.queue = make(chan postJob, postQueueSize)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range w.queue {
+				jobs, layout := w.gather(job)
+
+				w.post(jobs, layout)
 
-func (w postWriter) Write(p []byte) (n int, err error) {
-	if w != "" {
-		go func(url string, p []byte) {
-			res, err := client.Post(url, "application/json", bytes.NewReader(p))
+				w.mutex.Lock()
+				w.pending -= len(jobs)
 
-			if err != nil {
-				log.Printf("internal/logger postWriter error: %s", err.Error())
+				if w.pending == 0 {
+					w.idle.Broadcast()
+				}
+
+				w.mutex.Unlock()
 			}
+		}()
+	}
+}
+
+// gather returns first together with the jobs already queued behind it that can share its
+// request, and the layout of that request.
+func (w *PostWriter) gather(first postJob) ([]postJob, PostBody) {
+	w.mutex.Lock()
+	body := w.body
+	batch := w.batch
+	w.mutex.Unlock()
+
+	jobs := []postJob{first}
+
+	if body == PostSingle {
+		return jobs, body
+	}
 
-			if res == nil {
-				log.Print("internal/logger postWriter nil client response")
-				return
+	for len(jobs) < batch {
+		select {
+		case job, ok := <-w.queue:
+			if !ok {
+				return jobs, body
 			}
 
-			defer res.Body.Close()
+			jobs = append(jobs, job)
+		default:
+			return jobs, body
+		}
+	}
+
+	return jobs, body
+}
+
+// postBody lays out the records of jobs as the body of one request and returns its content
+// type.
+func postBody(jobs []postJob, layout PostBody) ([]byte, string) {
+	if layout == PostSingle {
+		return jobs[0].body, "application/json"
+	}
+
+	var buf bytes.Buffer
+
+	if layout == PostArray {
+		buf.WriteByte('[')
+	}
+
+	for i, job := range jobs {
+		record := bytes.TrimRight(job.body, "\n")
 
-			if res.StatusCode >= 300 {
-				log.Printf("internal/loggger postwriter error: %s", res.Status)
+		if layout == PostArray {
+			if i > 0 {
+				buf.WriteByte(',')
 			}
-		}(string(w), p)
+
+			buf.Write(record)
+			continue
+		}
+
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	if layout == PostArray {
+		buf.WriteByte(']')
+		return buf.Bytes(), "application/json"
+	}
+
+	return buf.Bytes(), "application/x-ndjson"
+}
+
+func (w *PostWriter) post(jobs []postJob, layout PostBody) {
+	w.mutex.Lock()
+	ctx := w.ctx
+	w.mutex.Unlock()
+
+	body, contentType := postBody(jobs, layout)
+	req, err := newPostRequest(ctx, w.url, body, contentType, jobs[0].compress)
+
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		w.fail(fmt.Errorf("log: collector responded %s", res.Status))
+	}
+}
+
+func (w *PostWriter) fail(err error) {
+	w.mutex.Lock()
+	fn := w.onError
+	w.mutex.Unlock()
+
+	if fn == nil {
+		log.Printf("internal/logger postWriter error: %s", err.Error())
+		return
+	}
+
+	fn(err)
+}
+
+// newPostRequest builds the request carrying body, gzipping it when compress is set.
+func newPostRequest(ctx context.Context, url string, body []byte, contentType string, compress bool) (*http.Request, error) {
+	if compress {
+		var buf bytes.Buffer
+
+		zw := gzip.NewWriter(&buf)
+
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
 	}
 
-	return fmt.Println(string(p))
+	return req, nil
+}
+
+// FileWriter writes encoded records to an open file such as os.Stderr. Writes are serialized,
+// so records from concurrent goroutines never interleave even when the file accepts a record
+// in several chunks.
+type FileWriter struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewFileWriter returns a writer for f. Loggers write to stdout by default; use it with
+// SetOutput to log to stderr instead, or with SetLevelWriter to also copy severe records there:
+//
+//	logger.SetOutput(log.NewFileWriter(os.Stderr))
+func NewFileWriter(f *os.File) *FileWriter {
+	return &FileWriter{file: f}
+}
+
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.file.Write(p)
 }
 
 type stdOutWriter struct{}
 
 func (s stdOutWriter) Write(p []byte) (n int, err error) {
-	return fmt.Println(string(p))
+	return os.Stdout.Write(p)
 }
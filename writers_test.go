@@ -0,0 +1,328 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// postRequest is a request received by a postCollector.
+type postRequest struct {
+	header http.Header
+	raw    []byte
+	body   []byte
+}
+
+// postCollector is an HTTP endpoint standing in for a log collector. It keeps every request
+// and gunzips the bodies that are compressed.
+type postCollector struct {
+	mutex    sync.Mutex
+	requests []postRequest
+}
+
+// newPostCollector starts a collector that calls handle, when it is not nil, for each request
+// before answering it.
+func newPostCollector(t *testing.T, handle func()) (*postCollector, *httptest.Server) {
+	c := &postCollector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+
+		if err != nil {
+			t.Errorf("collector: read body: %v", err)
+		}
+
+		body := raw
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(raw))
+
+			if err != nil {
+				t.Errorf("collector: gzip: %v", err)
+			} else if body, err = io.ReadAll(zr); err != nil {
+				t.Errorf("collector: gunzip: %v", err)
+			}
+		}
+
+		if handle != nil {
+			handle()
+		}
+
+		c.mutex.Lock()
+		c.requests = append(c.requests, postRequest{header: r.Header.Clone(), raw: raw, body: body})
+		c.mutex.Unlock()
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return c, srv
+}
+
+func (c *postCollector) received() []postRequest {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]postRequest(nil), c.requests...)
+}
+
+func TestPostWriterGzip(t *testing.T) {
+	c, srv := newPostCollector(t, nil)
+
+	w := NewPostWriter(srv.URL)
+	w.SetGzip(true)
+
+	record := []byte(`{"level":"info","msg":"compressed"}` + "\n")
+
+	if _, err := w.Write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := c.received()
+
+	if len(requests) != 1 {
+		t.Fatalf("collector got %d requests, want 1", len(requests))
+	}
+
+	req := requests[0]
+
+	if req.header.Get("Content-Encoding") != "gzip" || req.header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", req.header)
+	}
+
+	if bytes.Equal(req.raw, record) {
+		t.Error("body was sent uncompressed")
+	}
+
+	if !bytes.Equal(req.body, record) {
+		t.Errorf("gunzipped body = %q, want %q", req.body, record)
+	}
+}
+
+func TestPostWriterWithoutGzip(t *testing.T) {
+	c, srv := newPostCollector(t, nil)
+
+	w := NewPostWriter(srv.URL)
+	record := []byte(`{"msg":"plain"}` + "\n")
+
+	if _, err := w.Write(record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := c.received()[0]
+
+	if req.header.Get("Content-Encoding") != "" || !bytes.Equal(req.raw, record) {
+		t.Errorf("uncompressed request = %v %q", req.header, req.raw)
+	}
+}
+
+func TestPostWriterConcurrency(t *testing.T) {
+	var inFlight, most atomic.Int32
+
+	release := make(chan struct{})
+
+	c, srv := newPostCollector(t, func() {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := most.Load()
+
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		<-release
+	})
+
+	w := NewPostWriter(srv.URL)
+	w.SetConcurrency(2)
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte(`{"msg":"queued"}` + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for inFlight.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests in flight, want 2", inFlight.Load())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// give a third request the chance to start if the bound did not hold
+	time.Sleep(50 * time.Millisecond)
+
+	if w.Pending() != 10 {
+		t.Errorf("Pending = %d while every request is held, want 10", w.Pending())
+	}
+
+	close(release)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := most.Load(); got != 2 {
+		t.Errorf("%d requests were in flight at once, want at most 2", got)
+	}
+
+	if got := len(c.received()); got != 10 {
+		t.Errorf("collector got %d requests, want 10", got)
+	}
+}
+
+func TestPostWriterBody(t *testing.T) {
+	records := []string{`{"msg":"a"}`, `{"msg":"b"}`, `{"msg":"c"}`, `{"msg":"d"}`}
+
+	tests := []struct {
+		name        string
+		body        PostBody
+		batch       int
+		contentType string
+		want        []string
+	}{
+		{"single", PostSingle, 0, "application/json", records},
+		{"ndjson", PostNDJSON, 0, "application/x-ndjson", []string{
+			records[0] + "\n",
+			records[1] + "\n" + records[2] + "\n" + records[3] + "\n",
+		}},
+		{"array", PostArray, 0, "application/json", []string{
+			"[" + records[0] + "]",
+			"[" + records[1] + "," + records[2] + "," + records[3] + "]",
+		}},
+		{"array batch", PostArray, 2, "application/json", []string{
+			"[" + records[0] + "]",
+			"[" + records[1] + "," + records[2] + "]",
+			"[" + records[3] + "]",
+		}},
+	}
+
+	for _, tt := range tests {
+		var calls atomic.Int32
+
+		// hold the first request until the rest are queued behind it
+		release := make(chan struct{})
+
+		c, srv := newPostCollector(t, func() {
+			if calls.Add(1) == 1 {
+				<-release
+			}
+		})
+
+		w := NewPostWriter(srv.URL)
+		w.SetConcurrency(1)
+		w.SetBody(tt.body, tt.batch)
+
+		for i, record := range records {
+			if _, err := w.Write([]byte(record + "\n")); err != nil {
+				t.Fatal(err)
+			}
+
+			if i == 0 {
+				for calls.Load() == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}
+
+		close(release)
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		requests := c.received()
+		got := make([]string, len(requests))
+
+		for i, req := range requests {
+			got[i] = string(req.body)
+
+			if ct := req.header.Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("%s: Content-Type %q, want %q", tt.name, ct, tt.contentType)
+			}
+
+			if tt.body == PostArray {
+				var list []map[string]any
+
+				if err := json.Unmarshal(req.body, &list); err != nil {
+					t.Errorf("%s: body is not a JSON array: %v", tt.name, err)
+				}
+			}
+		}
+
+		if tt.body == PostSingle {
+			for i := range got {
+				got[i] = string(bytes.TrimRight([]byte(got[i]), "\n"))
+			}
+		}
+
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: bodies %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: body %d = %q, want %q", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "log")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	l := NewWithWriter("app", DebugLevel, NewFileWriter(f))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				l.With(Data{"worker": i}).Info("record %d", j)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	data, err := os.ReadFile(f.Name())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if records := decodeRecords(t, bytes.NewBuffer(data)); len(records) != 400 {
+		t.Errorf("read %d records back, want 400", len(records))
+	}
+}